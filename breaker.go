@@ -0,0 +1,203 @@
+package picodata
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/picodata/picodata-go/logger"
+)
+
+// CircuitState is the state of a per-instance circuit breaker.
+type CircuitState int32
+
+const (
+	// CircuitClosed means the instance receives traffic.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen means the instance is excluded from balancing after consecutive connection errors.
+	CircuitOpen
+	// CircuitHalfOpen means the instance is being probed and is still excluded from balancing.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig configures per-instance circuit breakers, see [WithCircuitBreaker].
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive connection errors that opens the circuit.
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before the instance is probed.
+	// It is also used as the probe timeout.
+	OpenTimeout time.Duration
+	// OnStateChange, if set, is called on every circuit state transition.
+	// It is called synchronously, so it must not block.
+	OnStateChange func(address string, from, to CircuitState)
+}
+
+// circuitBreaker excludes an instance from balancing after consecutive connection errors
+// and probes it in background until it responds again.
+type circuitBreaker struct {
+	address string
+	config  CircuitBreakerConfig
+	// probe checks whether the instance is reachable
+	probe func(ctx context.Context) error
+	// onClose is called when the instance becomes available again
+	onClose func()
+	logger  logger.Logger
+
+	// state is read on every balancing decision, so it is kept atomic
+	state atomic.Int32
+
+	mu       sync.Mutex
+	failures int
+	timer    *time.Timer
+	stopped  bool
+}
+
+func newCircuitBreaker(address string, config CircuitBreakerConfig, probe func(ctx context.Context) error, onClose func(), l logger.Logger) *circuitBreaker {
+	return &circuitBreaker{
+		address: address,
+		config:  config,
+		probe:   probe,
+		onClose: onClose,
+		logger:  l,
+	}
+}
+
+func (b *circuitBreaker) currentState() CircuitState {
+	return CircuitState(b.state.Load())
+}
+
+// allow reports whether the instance may receive traffic.
+func (b *circuitBreaker) allow() bool {
+	return b.currentState() == CircuitClosed
+}
+
+// onSuccess resets consecutive failures counter.
+func (b *circuitBreaker) onSuccess() {
+	b.mu.Lock()
+	b.failures = 0
+	b.mu.Unlock()
+}
+
+// onFailure registers a connection error and opens the circuit once the threshold is reached.
+func (b *circuitBreaker) onFailure() {
+	b.mu.Lock()
+	if b.stopped || b.currentState() != CircuitClosed {
+		b.mu.Unlock()
+		return
+	}
+
+	b.failures++
+	if b.failures < b.config.FailureThreshold {
+		b.mu.Unlock()
+		return
+	}
+
+	change := b.open()
+	b.mu.Unlock()
+
+	b.report(change)
+}
+
+// open excludes the instance and schedules a probe. Must be called with mu held.
+func (b *circuitBreaker) open() stateChange {
+	change := b.transition(CircuitOpen)
+	b.timer = time.AfterFunc(b.config.OpenTimeout, b.runProbe)
+	return change
+}
+
+func (b *circuitBreaker) runProbe() {
+	const op = "breaker: runProbe"
+
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return
+	}
+	change := b.transition(CircuitHalfOpen)
+	b.mu.Unlock()
+	b.report(change)
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.config.OpenTimeout)
+	err := b.probe(ctx)
+	cancel()
+
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return
+	}
+
+	if err != nil {
+		logger.LogFields(b.logger, logger.LevelDebug, "instance is still unavailable",
+			logger.String(logger.KeyOp, op), logger.String(logger.KeyAddress, b.address), logger.Err(err))
+		change = b.open()
+		b.mu.Unlock()
+		b.report(change)
+		return
+	}
+
+	b.failures = 0
+	change = b.transition(CircuitClosed)
+	b.mu.Unlock()
+
+	// NOTE: onClose takes the provider lock and removeConn stops breakers holding it,
+	// so onClose must be called without holding mu.
+	b.report(change)
+	b.onClose()
+}
+
+// stop cancels scheduled probes, it is called when the instance is removed from the provider.
+func (b *circuitBreaker) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.stopped = true
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+}
+
+// stateChange is a circuit state transition to be reported.
+type stateChange struct {
+	from, to CircuitState
+}
+
+// transition changes the state and returns the change to be reported with report. Must be called with mu held.
+func (b *circuitBreaker) transition(to CircuitState) stateChange {
+	from := CircuitState(b.state.Swap(int32(to)))
+	return stateChange{from: from, to: to}
+}
+
+// report logs the state change and calls OnStateChange.
+// NOTE: OnStateChange may call back into the pool, e.g. Topology takes the provider lock
+// and removeConn stops breakers holding it, so report must be called without holding mu.
+func (b *circuitBreaker) report(change stateChange) {
+	const op = "breaker: report"
+
+	from, to := change.from, change.to
+	if from == to {
+		return
+	}
+
+	logger.LogFields(b.logger, logger.LevelInfo, "circuit state changed",
+		logger.String(logger.KeyOp, op), logger.String(logger.KeyAddress, b.address),
+		logger.String("from", from.String()), logger.String(logger.KeyState, to.String()))
+
+	if b.config.OnStateChange != nil {
+		b.config.OnStateChange(b.address, from, to)
+	}
+}
@@ -0,0 +1,178 @@
+package picodata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/picodata/picodata-go/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stateTransition struct {
+	address  string
+	from, to CircuitState
+}
+
+// transitionRecorder collects circuit state transitions reported to OnStateChange
+type transitionRecorder struct {
+	mu          sync.Mutex
+	transitions []stateTransition
+}
+
+func (r *transitionRecorder) record(address string, from, to CircuitState) {
+	r.mu.Lock()
+	r.transitions = append(r.transitions, stateTransition{address, from, to})
+	r.mu.Unlock()
+}
+
+func (r *transitionRecorder) get() []stateTransition {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]stateTransition(nil), r.transitions...)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	connErr := fmt.Errorf("dial: %w", syscall.ECONNREFUSED)
+
+	t.Run("TestOpensAfterThreshold", func(t *testing.T) {
+		recorder := &transitionRecorder{}
+		config := CircuitBreakerConfig{FailureThreshold: 3, OpenTimeout: time.Hour, OnStateChange: recorder.record}
+		breaker := newCircuitBreaker("addr:1", config, func(context.Context) error { return nil }, func() {}, logger.Default())
+		defer breaker.stop()
+
+		breaker.onFailure()
+		breaker.onFailure()
+		// Success resets consecutive failures
+		breaker.onSuccess()
+		breaker.onFailure()
+		breaker.onFailure()
+		assert.True(t, breaker.allow())
+
+		breaker.onFailure()
+		assert.False(t, breaker.allow())
+		assert.Equal(t, CircuitOpen, breaker.currentState())
+		assert.Equal(t, []stateTransition{{"addr:1", CircuitClosed, CircuitOpen}}, recorder.get())
+	})
+
+	t.Run("TestProbe", func(t *testing.T) {
+		recorder := &transitionRecorder{}
+		config := CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: 10 * time.Millisecond, OnStateChange: recorder.record}
+
+		var mu sync.Mutex
+		probeErr := errors.New("still down")
+		probe := func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return probeErr
+		}
+		closed := make(chan struct{})
+		breaker := newCircuitBreaker("addr:1", config, probe, func() { close(closed) }, logger.Default())
+		defer breaker.stop()
+
+		breaker.onFailure()
+		assert.False(t, breaker.allow())
+
+		// Let the first probe fail, then bring the instance back
+		time.Sleep(30 * time.Millisecond)
+		assert.False(t, breaker.allow())
+		mu.Lock()
+		probeErr = nil
+		mu.Unlock()
+
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			t.Fatal("circuit is not closed after successful probe")
+		}
+
+		assert.True(t, breaker.allow())
+		transitions := recorder.get()
+		require.GreaterOrEqual(t, len(transitions), 4)
+		assert.Equal(t, stateTransition{"addr:1", CircuitClosed, CircuitOpen}, transitions[0])
+		assert.Equal(t, stateTransition{"addr:1", CircuitOpen, CircuitHalfOpen}, transitions[1])
+		assert.Equal(t, stateTransition{"addr:1", CircuitHalfOpen, CircuitClosed}, transitions[len(transitions)-1])
+	})
+
+	t.Run("TestStop", func(t *testing.T) {
+		config := CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: 10 * time.Millisecond}
+		probed := make(chan struct{}, 1)
+		breaker := newCircuitBreaker("addr:1", config, func(context.Context) error {
+			probed <- struct{}{}
+			return nil
+		}, func() {}, logger.Default())
+
+		breaker.onFailure()
+		breaker.stop()
+
+		select {
+		case <-probed:
+			t.Fatal("stopped breaker must not probe the instance")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("TestOnStateChangeCallsProvider", func(t *testing.T) {
+		prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+		require.NoError(t, prov.addConn("127.0.0.1:5433"))
+
+		// The hook stops the breaker that reported the transition, which must not deadlock
+		prov.setCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Hour, OnStateChange: func(address string, _, _ CircuitState) {
+			prov.removeConn(address)
+		}})
+
+		done := make(chan struct{})
+		go func() {
+			prov.connections[1].reportResult(connErr)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("OnStateChange must be called without holding the breaker lock")
+		}
+		assert.NotContains(t, prov.connsMap(), "127.0.0.1:5433")
+		prov.close()
+	})
+
+	t.Run("TestProviderExcludesOpenInstances", func(t *testing.T) {
+		prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+		require.NoError(t, prov.addConn("127.0.0.1:5433"))
+		prov.setCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Hour})
+		defer prov.close()
+
+		failing := prov.connections[0]
+		failing.reportResult(connErr)
+
+		for range 5 {
+			conn, err := prov.nextConnection(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, "127.0.0.1:5433", conn.address)
+		}
+
+		prov.connections[1].reportResult(connErr)
+		_, err := prov.nextConnection(context.Background())
+		assert.ErrorIs(t, err, ErrNoAvailableInstances)
+	})
+
+	t.Run("TestReportResult", func(t *testing.T) {
+		prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+		prov.setCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Hour})
+		defer prov.close()
+
+		inst := prov.connections[0]
+		// Neither query errors nor canceled operations say the instance is unreachable
+		inst.reportResult(errors.New("sbroad: table not found"))
+		inst.reportResult(context.Canceled)
+		assert.True(t, inst.available())
+
+		inst.reportResult(connErr)
+		assert.False(t, inst.available())
+	})
+}
@@ -0,0 +1,94 @@
+package picodata
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Conn is an acquired connection pinned to a single Picodata instance.
+// All statements executed through Conn are sent to the same backend,
+// which makes it suitable for session settings and multi-step reads.
+//
+// Conn must be returned to the pool with Release once it is no longer needed.
+type Conn struct {
+	conn    *pgxpool.Conn
+	address string
+}
+
+// Acquire returns a connection (*Conn) to the instance chosen by the balance strategy.
+func (p *Pool) Acquire(ctx context.Context) (*Conn, error) {
+	const op = "pool: Acquire"
+
+	var conn *Conn
+	err := p.withRetry(p.routingContext(ctx, RoutingModePreferLeader), spanAcquire, true, func(ctx context.Context, inst *instance) error {
+		c, err := inst.pool.Acquire(ctx)
+		if err != nil {
+			return err
+		}
+		conn = &Conn{conn: c, address: inst.address}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return conn, nil
+}
+
+// Address returns the address of the Picodata instance the connection is bound to.
+func (c *Conn) Address() string {
+	return c.address
+}
+
+// Release returns c to the instance pool it was acquired from.
+// Once Release has been called, other methods must not be called.
+func (c *Conn) Release() {
+	c.conn.Release()
+}
+
+// Hijack assumes ownership of the connection from the pool. Caller is responsible for closing the connection.
+// Hijack will panic if called on an already released or hijacked connection.
+func (c *Conn) Hijack() *pgx.Conn {
+	return c.conn.Hijack()
+}
+
+// Conn returns the underlying *pgx.Conn.
+func (c *Conn) Conn() *pgx.Conn {
+	return c.conn.Conn()
+}
+
+// Ping executes a simple SQL statement against the instance the connection is bound to.
+func (c *Conn) Ping(ctx context.Context) error {
+	// NOTE: see Pool.Ping for the reason we don't use the original Ping method.
+	_, err := c.conn.Exec(ctx, "SELECT 1")
+	return err
+}
+
+// Exec executes sql with args on the pinned instance. See [Pool.Exec] for details.
+func (c *Conn) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return c.conn.Exec(ctx, sql, args...)
+}
+
+// Query executes sql with args on the pinned instance. See [Pool.Query] for details.
+func (c *Conn) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return c.conn.Query(ctx, sql, args...)
+}
+
+// QueryRow executes sql with args on the pinned instance. See [Pool.QueryRow] for details.
+func (c *Conn) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return c.conn.QueryRow(ctx, sql, args...)
+}
+
+// SendBatch sends all queued queries of b to the pinned instance. See [Pool.SendBatch] for details.
+func (c *Conn) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return c.conn.SendBatch(ctx, b)
+}
+
+// CopyFrom performs a copy protocol operation on the pinned instance. See pgx.Conn.CopyFrom for details.
+func (c *Conn) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return c.conn.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
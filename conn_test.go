@@ -0,0 +1,73 @@
+package picodata
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConn(t *testing.T) {
+	t.Run("TestAcquireUnreachableInstance", func(t *testing.T) {
+		// Nothing listens on port 1, so acquiring must fail instead of returning a Conn
+		pool := &Pool{provider: newConnectionProvider(newMockPool("127.0.0.1", 1), 1)}
+
+		conn, err := pool.Acquire(context.Background())
+		require.Error(t, err)
+		assert.Nil(t, conn)
+	})
+
+	t.Run("TestPoolAddress", func(t *testing.T) {
+		pool := newMockPool("127.0.0.1", 5433)
+		assert.Equal(t, "127.0.0.1:5433", poolAddress(pool))
+	})
+}
+
+func TestIntegrationConn(t *testing.T) {
+	pool := newIntegrationPool(t, WithDisableTopologyManaging())
+	ctx := context.Background()
+
+	t.Run("TestPinnedToAddress", func(t *testing.T) {
+		conn, err := pool.Acquire(ctx)
+		require.NoError(t, err)
+		defer conn.Release()
+
+		config := conn.Conn().Config()
+		assert.Equal(t, conn.Address(), fmt.Sprintf("%s:%d", config.Host, config.Port))
+		assert.Contains(t, pool.provider.connsMap(), conn.Address())
+
+		for range 3 {
+			var one int
+			require.NoError(t, conn.QueryRow(ctx, "SELECT 1").Scan(&one))
+			assert.Equal(t, 1, one)
+		}
+		assert.NoError(t, conn.Ping(ctx))
+	})
+
+	t.Run("TestRelease", func(t *testing.T) {
+		conn, err := pool.Acquire(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), pool.Stat().AcquiredConns())
+
+		conn.Release()
+		assert.Equal(t, int32(0), pool.Stat().AcquiredConns())
+		assert.NotZero(t, pool.Stat().IdleConns())
+	})
+
+	t.Run("TestHijack", func(t *testing.T) {
+		conn, err := pool.Acquire(ctx)
+		require.NoError(t, err)
+		total := pool.Stat().TotalConns()
+
+		hijacked := conn.Hijack()
+		defer hijacked.Close(ctx)
+
+		// The pool no longer owns the connection, but it's still usable
+		assert.Equal(t, int32(0), pool.Stat().AcquiredConns())
+		assert.Equal(t, total-1, pool.Stat().TotalConns())
+		_, err = hijacked.Exec(ctx, "SELECT 1")
+		assert.NoError(t, err)
+	})
+}
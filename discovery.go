@@ -2,49 +2,102 @@ package picodata
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/picodata/picodata-go/logger"
 )
 
+// instanceRaftIDQuery returns the raft id of the instance serving the connection.
+const instanceRaftIDQuery = `SELECT raft_id FROM _pico_instance WHERE uuid = pico_instance_uuid()`
+
 // initialDiscovery performs a one-time topology discovery and populates
 // the connection provider with all online instances.
 func initialDiscovery(ctx context.Context, provider *connectionProvider) error {
 	const op = "discovery: initialDiscovery"
 
+	conn, err := provider.nextConnection(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	// Query topology
-	instances, err := getTopology(ctx, provider.nextConnection())
+	instances, err := getTopology(ctx, conn.pool)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	// Get initial connection address to skip it
-	initConfig := provider.config()
-	initAddr := fmt.Sprintf("%s:%d", initConfig.ConnConfig.Host, initConfig.ConnConfig.Port)
+	// Identify the instance serving the initial connection: its configured address
+	// may be an alias (e.g. localhost or a DNS name) of the address the cluster reports
+	initRaftID, err := getInstanceRaftID(ctx, conn.pool)
+	if err != nil {
+		logger.LogFields(provider.logger, logger.LevelWarn, "failed to identify the initial instance, matching it by address",
+			logger.String(logger.KeyOp, op), logger.Err(err))
+	}
+
+	added := addDiscovered(provider, instances, conn.address, initRaftID)
+
+	logger.LogFields(provider.logger, logger.LevelDebug, "initial discovery finished",
+		logger.String(logger.KeyOp, op), logger.Any("instances", len(instances)), logger.Any("added", added))
+
+	if provider.size() == 0 {
+		return fmt.Errorf("%s: no instances of the pool tiers: %w", op, ErrNoAvailableInstances)
+	}
+
+	return nil
+}
+
+// addDiscovered adds connections for all routable instances of the pool tiers
+// and returns the number of added connections. The initial instance, routed under initAddr,
+// is recognized by its raft id or, if initRaftID is 0, by its address. It is removed
+// if its tier isn't allowed and never added twice.
+func addDiscovered(provider *connectionProvider, instances []connState, initAddr string, initRaftID uint64) int {
+	const op = "discovery: addDiscovered"
 
-	// Add connections for all online instances (except the initial one)
 	added := 0
 	for _, inst := range instances {
-		// Skip non-online instances
-		if inst.currentState != stateOnline {
+		provider.updateState(inst)
+
+		initial := inst.address == initAddr || (initRaftID != 0 && inst.meta.raftID == initRaftID)
+
+		// Skip instances of other tiers. The initial one has been used for discovery only
+		if !provider.tierAllowed(inst.meta.tier) {
+			if initial {
+				provider.removeConn(initAddr)
+			}
+			continue
+		}
+		// Skip instances that can't receive traffic
+		if !routable(inst.currentState, inst.targetState) {
 			continue
 		}
 		// Skip the initial connection (already added)
-		if inst.address == initAddr {
+		if initial {
 			continue
 		}
 
 		if err := provider.addConn(inst.address); err != nil {
-			logger.Log(logger.LevelError, "%s: failed to add connection for %s: %v", op, inst.address, err)
+			logger.LogFields(provider.logger, logger.LevelError, "failed to add connection",
+				logger.String(logger.KeyOp, op), logger.String(logger.KeyAddress, inst.address), logger.Err(err))
 			continue
 		}
 		added++
 	}
 
-	logger.Log(logger.LevelDebug, "%s: discovered %d instances, added %d connections", op, len(instances), added)
+	return added
+}
+
+// getInstanceRaftID returns the raft id of the instance serving conn.
+func getInstanceRaftID(ctx context.Context, conn *pgxpool.Pool) (uint64, error) {
+	const op = "discovery: getInstanceRaftID"
 
-	return nil
+	var raftID uint64
+	if err := conn.QueryRow(ctx, instanceRaftIDQuery).Scan(&raftID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return raftID, nil
 }
 
 // getTopology queries the cluster topology and returns all instances
@@ -61,18 +114,32 @@ func getTopology(ctx context.Context, conn *pgxpool.Pool) ([]connState, error) {
 
 	for rows.Next() {
 		var connAddr string
-		var connFetchedState []any // contains [string, int]
+		var connFetchedState, connFetchedTargetState []any // contains [string, int]
+		var connFetchedFailureDomain []byte                // contains a JSON object
+		var connFetchedMasterName *string                  // NULL if the replicaset isn't created yet
+		var meta instanceMeta
 
-		if err := rows.Scan(&connAddr, &connFetchedState); err != nil {
+		if err := rows.Scan(&connAddr, &connFetchedState, &connFetchedTargetState, &meta.raftID, &meta.name, &meta.replicasetName, &meta.tier, &connFetchedFailureDomain, &connFetchedMasterName); err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
+		meta.leader = connFetchedMasterName != nil && *connFetchedMasterName == meta.name
+
+		currentState, err := parseState(connFetchedState)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s current state %w", op, connAddr, err)
+		}
+
+		targetState, err := parseState(connFetchedTargetState)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s target state %w", op, connAddr, err)
+		}
 
-		connStateStr, ok := connFetchedState[0].(string)
-		if !ok {
-			return nil, fmt.Errorf("%s: %s state must be a string, but has type %T", op, connAddr, connFetchedState[0])
+		meta.failureDomain, err = parseFailureDomain(connFetchedFailureDomain)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s failure domain %w", op, connAddr, err)
 		}
 
-		instances = append(instances, connState{address: connAddr, currentState: connStateStr})
+		instances = append(instances, connState{address: connAddr, currentState: currentState, targetState: targetState, meta: meta})
 	}
 
 	if err := rows.Err(); err != nil {
@@ -81,3 +148,33 @@ func getTopology(ctx context.Context, conn *pgxpool.Pool) ([]connState, error) {
 
 	return instances, nil
 }
+
+// parseState extracts the state variant from a fetched [variant, incarnation] pair.
+func parseState(fetchedState []any) (InstanceState, error) {
+	if len(fetchedState) == 0 {
+		return "", fmt.Errorf("is empty")
+	}
+
+	state, ok := fetchedState[0].(string)
+	if !ok {
+		return "", fmt.Errorf("must be a string, but has type %T", fetchedState[0])
+	}
+
+	return InstanceState(state), nil
+}
+
+// parseFailureDomain decodes a fetched failure domain, e.g. {"REGION": "EU", "ZONE": "EU-1"}.
+// It returns nil if the instance has no failure domain.
+func parseFailureDomain(fetchedDomain []byte) (map[string]string, error) {
+	var domain map[string]string
+	if len(fetchedDomain) != 0 {
+		if err := json.Unmarshal(fetchedDomain, &domain); err != nil {
+			return nil, fmt.Errorf("must be an object of strings: %w", err)
+		}
+	}
+
+	if len(domain) == 0 {
+		return nil, nil
+	}
+	return domain, nil
+}
@@ -0,0 +1,44 @@
+package picodata
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddDiscovered(t *testing.T) {
+	// The initial instance is configured by an alias of the address reported by the cluster
+	discovered := []connState{
+		{address: "127.0.0.1:5432", currentState: InstanceStateOnline, targetState: InstanceStateOnline, meta: instanceMeta{raftID: 1, tier: "storage"}},
+		{address: "127.0.0.1:5433", currentState: InstanceStateOnline, targetState: InstanceStateOnline, meta: instanceMeta{raftID: 2, tier: "router"}},
+	}
+
+	t.Run("TestInitialNotAddedTwice", func(t *testing.T) {
+		prov := newConnectionProvider(newMockPool("localhost", 5432), 1)
+		defer prov.close()
+
+		assert.Equal(t, 1, addDiscovered(prov, discovered, "localhost:5432", 1))
+		assert.ElementsMatch(t, []string{"localhost:5432", "127.0.0.1:5433"}, slices.Collect(maps.Keys(prov.connsMap())))
+	})
+
+	t.Run("TestInitialTierFiltered", func(t *testing.T) {
+		prov := newConnectionProvider(newMockPool("localhost", 5432), 1)
+		prov.setTiers([]string{"router"})
+		defer prov.close()
+
+		assert.Equal(t, 1, addDiscovered(prov, discovered, "localhost:5432", 1))
+		assert.ElementsMatch(t, []string{"127.0.0.1:5433"}, slices.Collect(maps.Keys(prov.connsMap())))
+	})
+
+	t.Run("TestInitialMatchedByAddress", func(t *testing.T) {
+		prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+		prov.setTiers([]string{"router"})
+		defer prov.close()
+
+		// The raft id of the initial instance is unknown
+		assert.Equal(t, 1, addDiscovered(prov, discovered, "127.0.0.1:5432", 0))
+		assert.ElementsMatch(t, []string{"127.0.0.1:5433"}, slices.Collect(maps.Keys(prov.connsMap())))
+	})
+}
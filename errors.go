@@ -0,0 +1,49 @@
+package picodata
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrNoAvailableInstances is returned when the pool has no instance to route an operation to.
+var ErrNoAvailableInstances = errors.New("picodata: no available instances")
+
+var (
+	_ pgx.Rows         = (*errRows)(nil)
+	_ pgx.Row          = (*errRow)(nil)
+	_ pgx.BatchResults = (*errBatchResults)(nil)
+)
+
+// errRows is a pgx.Rows in error state, returned when the query can't be sent to any instance.
+type errRows struct {
+	err error
+}
+
+func (errRows) Close()                                       {}
+func (e errRows) Err() error                                 { return e.err }
+func (errRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (errRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (errRows) Next() bool                                   { return false }
+func (e errRows) Scan(dest ...any) error                     { return e.err }
+func (e errRows) Values() ([]any, error)                     { return nil, e.err }
+func (e errRows) RawValues() [][]byte                        { return nil }
+func (e errRows) Conn() *pgx.Conn                            { return nil }
+
+// errRow is a pgx.Row in error state, the error is returned on Scan.
+type errRow struct {
+	err error
+}
+
+func (e errRow) Scan(dest ...any) error { return e.err }
+
+// errBatchResults is a pgx.BatchResults in error state, every method returns the error.
+type errBatchResults struct {
+	err error
+}
+
+func (br errBatchResults) Exec() (pgconn.CommandTag, error) { return pgconn.CommandTag{}, br.err }
+func (br errBatchResults) Query() (pgx.Rows, error)         { return errRows{err: br.err}, br.err }
+func (br errBatchResults) QueryRow() pgx.Row                { return errRow{err: br.err} }
+func (br errBatchResults) Close() error                     { return br.err }
@@ -1,11 +1,64 @@
 package picodata
 
+import "maps"
+
+// InstanceState is the state of a Picodata instance as reported by _pico_instance.
+type InstanceState string
+
 const (
-	stateOnline  = "Online"
-	stateOffline = "Offline"
+	InstanceStateOffline  InstanceState = "Offline"
+	InstanceStateOnline   InstanceState = "Online"
+	InstanceStateExpelled InstanceState = "Expelled"
+
+	// Intermediate states older Picodata versions report while an instance is joining the cluster.
+	InstanceStateRaftSynced          InstanceState = "RaftSynced"
+	InstanceStateReplicated          InstanceState = "Replicated"
+	InstanceStateShardingInitialized InstanceState = "ShardingInitialized"
 )
 
+// Known reports whether s is one of the states defined above.
+func (s InstanceState) Known() bool {
+	switch s {
+	case InstanceStateOffline, InstanceStateOnline, InstanceStateExpelled,
+		InstanceStateRaftSynced, InstanceStateReplicated, InstanceStateShardingInitialized:
+		return true
+	default:
+		return false
+	}
+}
+
+// routable reports whether an instance with given current and target states may receive traffic.
+// An instance must be online and not going to leave that state: target state differs from
+// the current one while the instance is being shut down or expelled.
+// Empty target state means it is unknown and is not taken into account.
+func routable(current, target InstanceState) bool {
+	return current == InstanceStateOnline && (target == "" || target == InstanceStateOnline)
+}
+
+// instanceMeta describes the place of an instance in the cluster.
+type instanceMeta struct {
+	raftID         uint64
+	name           string
+	replicasetName string
+	tier           string
+	// failureDomain is never modified once fetched, so it may be shared
+	failureDomain map[string]string
+	// leader reports whether the instance is the current master of its replicaset
+	leader bool
+}
+
+func (m instanceMeta) equal(other instanceMeta) bool {
+	return m.raftID == other.raftID &&
+		m.name == other.name &&
+		m.replicasetName == other.replicasetName &&
+		m.tier == other.tier &&
+		maps.Equal(m.failureDomain, other.failureDomain) &&
+		m.leader == other.leader
+}
+
 type event struct {
-	address string
-	state   string
+	address     string
+	state       InstanceState
+	targetState InstanceState
+	meta        instanceMeta
 }
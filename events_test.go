@@ -0,0 +1,115 @@
+package picodata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstanceState(t *testing.T) {
+	t.Run("TestRoutable", func(t *testing.T) {
+		cases := []struct {
+			current, target InstanceState
+			want            bool
+		}{
+			{InstanceStateOnline, InstanceStateOnline, true},
+			{InstanceStateOnline, "", true},
+			{InstanceStateOnline, InstanceStateOffline, false},
+			{InstanceStateOnline, InstanceStateExpelled, false},
+			{InstanceStateOffline, InstanceStateOnline, false},
+			{InstanceStateOffline, InstanceStateOffline, false},
+			{InstanceStateExpelled, InstanceStateExpelled, false},
+			{InstanceStateReplicated, InstanceStateOnline, false},
+			{"Unknown", InstanceStateOnline, false},
+		}
+
+		for _, c := range cases {
+			assert.Equal(t, c.want, routable(c.current, c.target), "%s -> %s", c.current, c.target)
+		}
+	})
+
+	t.Run("TestKnown", func(t *testing.T) {
+		assert.True(t, InstanceStateOnline.Known())
+		assert.True(t, InstanceStateExpelled.Known())
+		assert.True(t, InstanceStateShardingInitialized.Known())
+		assert.False(t, InstanceState("Unknown").Known())
+		assert.False(t, InstanceState("").Known())
+	})
+
+	t.Run("TestParseState", func(t *testing.T) {
+		state, err := parseState([]any{"Online", int64(3)})
+		assert.NoError(t, err)
+		assert.Equal(t, InstanceStateOnline, state)
+
+		_, err = parseState(nil)
+		assert.Error(t, err)
+		_, err = parseState([]any{int64(1), int64(3)})
+		assert.Error(t, err)
+	})
+
+	t.Run("TestParseFailureDomain", func(t *testing.T) {
+		domain, err := parseFailureDomain([]byte(`{"REGION": "EU", "ZONE": "EU-1"}`))
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"REGION": "EU", "ZONE": "EU-1"}, domain)
+
+		for _, empty := range []string{"", "null", "{}"} {
+			domain, err = parseFailureDomain([]byte(empty))
+			assert.NoError(t, err)
+			assert.Nil(t, domain, empty)
+		}
+
+		_, err = parseFailureDomain([]byte(`["EU"]`))
+		assert.Error(t, err)
+	})
+
+	t.Run("TestStateFilter", func(t *testing.T) {
+		filter := newStateFilter(connState{address: "a:1", currentState: InstanceStateOnline, targetState: InstanceStateOnline})
+
+		got := filter.filterNewOrUpdated([]connState{
+			{address: "a:1", currentState: InstanceStateOnline, targetState: InstanceStateOnline},
+			{address: "b:1", currentState: InstanceStateOnline, targetState: InstanceStateOnline},
+		})
+		assert.Equal(t, []connState{{address: "b:1", currentState: InstanceStateOnline, targetState: InstanceStateOnline}}, got)
+
+		// Change of the target state only is reported as well
+		got = filter.filterNewOrUpdated([]connState{
+			{address: "a:1", currentState: InstanceStateOnline, targetState: InstanceStateOffline},
+			{address: "b:1", currentState: InstanceStateOnline, targetState: InstanceStateOnline},
+		})
+		assert.Equal(t, []connState{{address: "a:1", currentState: InstanceStateOnline, targetState: InstanceStateOffline}}, got)
+
+		// So is the change of the failure domain
+		moved := connState{
+			address:      "b:1",
+			currentState: InstanceStateOnline,
+			targetState:  InstanceStateOnline,
+			meta:         instanceMeta{failureDomain: map[string]string{"ZONE": "EU-1"}},
+		}
+		offline := connState{address: "a:1", currentState: InstanceStateOnline, targetState: InstanceStateOffline}
+		assert.Equal(t, []connState{moved}, filter.filterNewOrUpdated([]connState{offline, moved}))
+		assert.Empty(t, filter.filterNewOrUpdated([]connState{offline, moved}))
+	})
+
+	t.Run("TestStateFilterRemoved", func(t *testing.T) {
+		filter := newStateFilter(connState{address: "seed:1", currentState: InstanceStateOnline, targetState: InstanceStateOnline})
+		a := connState{address: "a:1", currentState: InstanceStateOnline, targetState: InstanceStateOnline, meta: instanceMeta{raftID: 1}}
+		b := connState{address: "b:1", currentState: InstanceStateOnline, targetState: InstanceStateOnline, meta: instanceMeta{raftID: 2}}
+
+		// The initial address isn't removed until the cluster has reported it
+		assert.Equal(t, []connState{a, b}, filter.filterNewOrUpdated([]connState{a, b}))
+		assert.Empty(t, filter.filterRemoved([]connState{a, b}))
+
+		// The address of an instance changed, or its row was deleted
+		moved := connState{address: "a:2", currentState: InstanceStateOnline, targetState: InstanceStateOnline, meta: instanceMeta{raftID: 1}}
+		filter.filterNewOrUpdated([]connState{moved})
+		got := filter.filterRemoved([]connState{moved})
+		assert.ElementsMatch(t, []connState{
+			{address: "a:1", currentState: InstanceStateOffline, targetState: InstanceStateOffline, meta: instanceMeta{raftID: 1}},
+			{address: "b:1", currentState: InstanceStateOffline, targetState: InstanceStateOffline, meta: instanceMeta{raftID: 2}},
+		}, got)
+		assert.Empty(t, filter.filterRemoved([]connState{moved}))
+
+		// A returning address is reported again
+		assert.Equal(t, []connState{b}, filter.filterNewOrUpdated([]connState{moved, b}))
+	})
+}
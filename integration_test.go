@@ -0,0 +1,60 @@
+package picodata
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/network"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// newIntegrationPool returns a pool connected to the CI cluster or, when run locally, to a Picodata container.
+// The pool is closed when the test finishes.
+func newIntegrationPool(t *testing.T, opts ...PoolOption) *Pool {
+	connString := createPsql(os.Getenv("PICODATA_ADMIN_PASSWORD"), "picodata-1:5432")
+	if !*ciFlag {
+		connString = runPicodataContainer(t)
+	}
+
+	pool, err := New(context.Background(), connString, opts...)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
+// runPicodataContainer starts a single instance cluster terminated when the test finishes
+// and returns the connection string of the instance.
+func runPicodataContainer(t *testing.T) string {
+	newNetwork, err := network.New(context.Background())
+	require.NoError(t, err)
+	testcontainers.CleanupNetwork(t, newNetwork)
+
+	req := testcontainers.ContainerRequest{
+		Image:    "docker-public.binary.picodata.io/picodata:master",
+		Name:     "picodata-1-1",
+		Hostname: "picodata-1-1",
+		Env: map[string]string{
+			"PICODATA_PEER":           "picodata-1-1:3301",
+			"PICODATA_LISTEN":         "picodata-1-1:3301",
+			"PICODATA_ADVERTISE":      "picodata-1-1:3301",
+			"PICODATA_PG_LISTEN":      "0.0.0.0:55432",
+			"PICODATA_ADMIN_PASSWORD": adminPassword,
+			"PICODATA_LOG_LEVEL":      "info",
+		},
+		ExposedPorts: []string{"55432:55432"},
+		WaitingFor:   wait.ForLog("Discovery enters idle mode, all buckets are known. Discovery works with 10 seconds interval now"),
+		Networks:     []string{newNetwork.Name},
+	}
+	c, err := testcontainers.GenericContainer(context.Background(), testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	testcontainers.CleanupContainer(t, c)
+	require.NoError(t, err)
+
+	return createPsql(adminPassword, "0.0.0.0:55432")
+}
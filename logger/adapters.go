@@ -0,0 +1,194 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	_ StructuredLogger = (*SlogLogger)(nil)
+	_ StructuredLogger = (*SugaredAdapter)(nil)
+	_ StructuredLogger = (*FuncLogger)(nil)
+	_ StructuredLogger = (*LevelLogger)(nil)
+)
+
+// levelFilter drops records above the level. Adapters start with LevelDebug,
+// so filtering is left to the wrapped logger until SetLevel is called.
+type levelFilter struct {
+	level atomic.Int32
+}
+
+func (f *levelFilter) init() {
+	f.level.Store(int32(LevelDebug))
+}
+
+func (f *levelFilter) SetLevel(level LogLevel) error {
+	if _, err := level.String(); err != nil {
+		return err
+	}
+	f.level.Store(int32(level))
+	return nil
+}
+
+func (f *levelFilter) enabled(level LogLevel) bool {
+	return level != LevelNone && int32(level) <= f.level.Load()
+}
+
+// SlogLogger writes records to a slog.Handler.
+type SlogLogger struct {
+	levelFilter
+	handler slog.Handler
+}
+
+// NewSlogLogger creates a logger writing to handler, e.g. slog.Default().Handler().
+func NewSlogLogger(handler slog.Handler) *SlogLogger {
+	l := &SlogLogger{handler: handler}
+	l.init()
+	return l
+}
+
+func (l *SlogLogger) Log(level LogLevel, msg string, fields ...any) {
+	l.LogFields(level, fmt.Sprintf(msg, fields...))
+}
+
+func (l *SlogLogger) LogFields(level LogLevel, msg string, fields ...Field) {
+	if !l.enabled(level) {
+		return
+	}
+
+	ctx := context.Background()
+	slogLevel := toSlogLevel(level)
+	if !l.handler.Enabled(ctx, slogLevel) {
+		return
+	}
+
+	// NOTE: the caller's pc is not recorded, AddSource would point to the adapter.
+	record := slog.NewRecord(time.Now(), slogLevel, msg, 0)
+	for _, f := range fields {
+		record.AddAttrs(slog.Any(f.Key, f.Value))
+	}
+	_ = l.handler.Handle(ctx, record)
+}
+
+func toSlogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LevelError:
+		return slog.LevelError
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelInfo:
+		return slog.LevelInfo
+	default:
+		return slog.LevelDebug
+	}
+}
+
+// SugaredLogger is implemented by loggers with key/value methods, such as *zap.SugaredLogger.
+type SugaredLogger interface {
+	Debugw(msg string, keysAndValues ...any)
+	Infow(msg string, keysAndValues ...any)
+	Warnw(msg string, keysAndValues ...any)
+	Errorw(msg string, keysAndValues ...any)
+}
+
+// SugaredAdapter writes records to a SugaredLogger.
+type SugaredAdapter struct {
+	levelFilter
+	logger SugaredLogger
+}
+
+// NewSugaredLogger creates a logger writing to a zap-style sugared logger:
+//
+//	l := logger.NewSugaredLogger(zapLogger.Sugar())
+func NewSugaredLogger(logger SugaredLogger) *SugaredAdapter {
+	l := &SugaredAdapter{logger: logger}
+	l.init()
+	return l
+}
+
+func (l *SugaredAdapter) Log(level LogLevel, msg string, fields ...any) {
+	l.LogFields(level, fmt.Sprintf(msg, fields...))
+}
+
+func (l *SugaredAdapter) LogFields(level LogLevel, msg string, fields ...Field) {
+	if !l.enabled(level) {
+		return
+	}
+
+	kv := KeysAndValues(fields)
+	switch level {
+	case LevelError:
+		l.logger.Errorw(msg, kv...)
+	case LevelWarn:
+		l.logger.Warnw(msg, kv...)
+	case LevelInfo:
+		l.logger.Infow(msg, kv...)
+	default:
+		l.logger.Debugw(msg, kv...)
+	}
+}
+
+// LogFunc writes a single structured record.
+type LogFunc func(level LogLevel, msg string, fields []Field)
+
+// FuncLogger writes records with a LogFunc. It fits loggers with chained APIs, such as zerolog:
+//
+//	l := logger.NewFuncLogger(func(level logger.LogLevel, msg string, fields []logger.Field) {
+//		zl.WithLevel(toZerologLevel(level)).Fields(logger.KeysAndValues(fields)).Msg(msg)
+//	})
+type FuncLogger struct {
+	levelFilter
+	fn LogFunc
+}
+
+// NewFuncLogger creates a logger calling fn for every record.
+func NewFuncLogger(fn LogFunc) *FuncLogger {
+	l := &FuncLogger{fn: fn}
+	l.init()
+	return l
+}
+
+func (l *FuncLogger) Log(level LogLevel, msg string, fields ...any) {
+	l.LogFields(level, fmt.Sprintf(msg, fields...))
+}
+
+func (l *FuncLogger) LogFields(level LogLevel, msg string, fields ...Field) {
+	if !l.enabled(level) {
+		return
+	}
+	l.fn(level, msg, fields)
+}
+
+// LevelLogger drops records above its level and passes the rest to the wrapped logger.
+// SetLevel changes only the level of the wrapper, so a logger shared by several pools
+// can be used with a different level by each of them.
+type LevelLogger struct {
+	levelFilter
+	logger Logger
+}
+
+// NewLevelLogger creates a logger passing records up to level to l.
+func NewLevelLogger(l Logger, level LogLevel) (*LevelLogger, error) {
+	ll := &LevelLogger{logger: l}
+	if err := ll.SetLevel(level); err != nil {
+		return nil, err
+	}
+	return ll, nil
+}
+
+func (l *LevelLogger) Log(level LogLevel, msg string, fields ...any) {
+	if !l.enabled(level) {
+		return
+	}
+	l.logger.Log(level, msg, fields...)
+}
+
+func (l *LevelLogger) LogFields(level LogLevel, msg string, fields ...Field) {
+	if !l.enabled(level) {
+		return
+	}
+	LogFields(l.logger, level, msg, fields...)
+}
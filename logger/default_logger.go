@@ -3,37 +3,77 @@ package logger
 import (
 	"fmt"
 	"log"
+	"sync"
+	"sync/atomic"
 )
 
-var _ Logger = (*defaultLogger)(nil)
+var (
+	_ StructuredLogger = (*defaultLogger)(nil)
+	_ StructuredLogger = global{}
+)
+
+var (
+	globalMu     sync.RWMutex
+	globalLogger Logger = NewDefaultLogger()
+)
+
+// SetDefaultLogger replaces the process-wide logger used by pools created without WithLogger.
+func SetDefaultLogger(logger Logger) {
+	globalMu.Lock()
+	globalLogger = logger
+	globalMu.Unlock()
+}
+
+// SetLevel sets the level of the process-wide logger.
+func SetLevel(level LogLevel) error { return getDefaultLogger().SetLevel(level) }
+
+// Log logs with the process-wide logger.
+func Log(level LogLevel, msg string, fields ...any) { getDefaultLogger().Log(level, msg, fields...) }
+
+// Default returns a logger forwarding to the process-wide logger,
+// so it follows replacements made by SetDefaultLogger.
+func Default() Logger {
+	return global{}
+}
+
+func getDefaultLogger() Logger {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+
+	return globalLogger
+}
 
-var globalLogger Logger = newDefaultLogger()
+// global forwards to the process-wide logger.
+type global struct{}
 
-func SetDefaultLogger(logger Logger)                { globalLogger = logger }
-func SetLevel(level LogLevel) error                 { return globalLogger.SetLevel(level) }
-func Log(level LogLevel, msg string, fields ...any) { globalLogger.Log(level, msg, fields...) }
+func (global) Log(level LogLevel, msg string, fields ...any) { Log(level, msg, fields...) }
+func (global) SetLevel(level LogLevel) error                 { return SetLevel(level) }
+func (global) LogFields(level LogLevel, msg string, fields ...Field) {
+	LogFields(getDefaultLogger(), level, msg, fields...)
+}
 
 type defaultLogger struct {
-	level LogLevel
+	level atomic.Int32
 }
 
-func newDefaultLogger() *defaultLogger {
-	return &defaultLogger{
-		level: LevelInfo,
-	}
+// NewDefaultLogger creates a logger writing to the standard log package with LevelInfo.
+func NewDefaultLogger() Logger {
+	l := &defaultLogger{}
+	l.level.Store(int32(LevelInfo))
+	return l
 }
 
 func (l *defaultLogger) SetLevel(level LogLevel) error {
 	if _, err := level.String(); err != nil {
 		return err
 	}
-	l.level = level
+	l.level.Store(int32(level))
 
 	return nil
 }
 
 func (l *defaultLogger) Log(level LogLevel, msg string, fields ...any) {
-	if level > l.level {
+	if int32(level) > l.level.Load() {
 		return
 	}
 	// NOTE: We can ignore error handling, because the default one is already valid.
@@ -41,3 +81,7 @@ func (l *defaultLogger) Log(level LogLevel, msg string, fields ...any) {
 	levelStr, _ := level.String()
 	log.Printf("[%s] %s\n", levelStr, fmt.Sprintf(msg, fields...))
 }
+
+func (l *defaultLogger) LogFields(level LogLevel, msg string, fields ...Field) {
+	l.Log(level, "%s", formatFields(msg, fields))
+}
@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Keys of the fields pool components log with.
+const (
+	KeyOp      = "op"
+	KeyAddress = "address"
+	KeyState   = "state"
+	KeyErr     = "err"
+)
+
+// Field is a key/value pair attached to a structured log record.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// String returns a field with a string value.
+func String(key, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Any returns a field with an arbitrary value.
+func Any(key string, value any) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Err returns a field with the err key.
+func Err(err error) Field {
+	return Field{Key: KeyErr, Value: err}
+}
+
+// StructuredLogger is a Logger that keeps fields of a record separate from its message.
+type StructuredLogger interface {
+	Logger
+	LogFields(level LogLevel, msg string, fields ...Field)
+}
+
+// LogFields logs msg with fields using l. If l is not a StructuredLogger,
+// fields are appended to the message as key=value pairs.
+func LogFields(l Logger, level LogLevel, msg string, fields ...Field) {
+	if sl, ok := l.(StructuredLogger); ok {
+		sl.LogFields(level, msg, fields...)
+		return
+	}
+
+	l.Log(level, "%s", formatFields(msg, fields))
+}
+
+// KeysAndValues flattens fields into alternating keys and values,
+// the form most structured logging libraries accept.
+func KeysAndValues(fields []Field) []any {
+	kv := make([]any, 0, len(fields)*2)
+	for _, f := range fields {
+		kv = append(kv, f.Key, f.Value)
+	}
+	return kv
+}
+
+func formatFields(msg string, fields []Field) string {
+	if len(fields) == 0 {
+		return msg
+	}
+
+	var b strings.Builder
+	b.WriteString(msg)
+	for _, f := range fields {
+		fmt.Fprintf(&b, " %s=%v", f.Key, f.Value)
+	}
+	return b.String()
+}
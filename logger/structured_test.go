@@ -0,0 +1,118 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/picodata/picodata-go/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// printfLogger is a plain Logger without structured support
+type printfLogger struct {
+	lines []string
+}
+
+func (l *printfLogger) Log(_ logger.LogLevel, msg string, fields ...any) {
+	l.lines = append(l.lines, fmt.Sprintf(msg, fields...))
+}
+
+func (l *printfLogger) SetLevel(logger.LogLevel) error { return nil }
+
+type sugaredRecorder struct {
+	calls []string
+	kv    []any
+}
+
+func (r *sugaredRecorder) record(level, msg string, kv []any) {
+	r.calls = append(r.calls, level+" "+msg)
+	r.kv = kv
+}
+
+func (r *sugaredRecorder) Debugw(msg string, kv ...any) { r.record("debug", msg, kv) }
+func (r *sugaredRecorder) Infow(msg string, kv ...any)  { r.record("info", msg, kv) }
+func (r *sugaredRecorder) Warnw(msg string, kv ...any)  { r.record("warn", msg, kv) }
+func (r *sugaredRecorder) Errorw(msg string, kv ...any) { r.record("error", msg, kv) }
+
+func TestStructuredLogging(t *testing.T) {
+	fields := []logger.Field{
+		logger.String(logger.KeyOp, "provider: addConn"),
+		logger.String(logger.KeyAddress, "127.0.0.1:5432"),
+		logger.Err(errors.New("refused")),
+	}
+
+	t.Run("TestSlogLogger", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := logger.NewSlogLogger(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+		logger.LogFields(l, logger.LevelWarn, "instance added", fields...)
+
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+		assert.Equal(t, "WARN", record["level"])
+		assert.Equal(t, "instance added", record["msg"])
+		assert.Equal(t, "provider: addConn", record["op"])
+		assert.Equal(t, "127.0.0.1:5432", record["address"])
+		assert.Equal(t, "refused", record["err"])
+
+		buf.Reset()
+		require.NoError(t, l.SetLevel(logger.LevelError))
+		logger.LogFields(l, logger.LevelWarn, "filtered", fields...)
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("TestSugaredLogger", func(t *testing.T) {
+		rec := &sugaredRecorder{}
+		l := logger.NewSugaredLogger(rec)
+
+		logger.LogFields(l, logger.LevelError, "failed", fields...)
+		l.Log(logger.LevelDebug, "printf %d", 1)
+
+		assert.Equal(t, []string{"error failed", "debug printf 1"}, rec.calls)
+		assert.Empty(t, rec.kv)
+	})
+
+	t.Run("TestFuncLogger", func(t *testing.T) {
+		var got []logger.Field
+		l := logger.NewFuncLogger(func(level logger.LogLevel, msg string, fields []logger.Field) {
+			got = fields
+		})
+
+		logger.LogFields(l, logger.LevelInfo, "msg", fields...)
+		assert.Equal(t, fields, got)
+		assert.Equal(t, []any{"op", "provider: addConn", "address", "127.0.0.1:5432", "err", fields[2].Value}, logger.KeysAndValues(got))
+	})
+
+	t.Run("TestPlainLoggerFallback", func(t *testing.T) {
+		l := &printfLogger{}
+
+		logger.LogFields(l, logger.LevelInfo, "instance added", fields[:2]...)
+		assert.Equal(t, []string{"instance added op=provider: addConn address=127.0.0.1:5432"}, l.lines)
+	})
+
+	t.Run("TestLevelLogger", func(t *testing.T) {
+		var levels []logger.LogLevel
+		shared := logger.NewFuncLogger(func(level logger.LogLevel, msg string, fields []logger.Field) {
+			levels = append(levels, level)
+		})
+
+		_, err := logger.NewLevelLogger(shared, logger.LogLevel(100))
+		assert.Error(t, err)
+
+		l, err := logger.NewLevelLogger(shared, logger.LevelWarn)
+		require.NoError(t, err)
+
+		logger.LogFields(l, logger.LevelError, "msg", fields...)
+		logger.LogFields(l, logger.LevelInfo, "msg", fields...)
+		l.Log(logger.LevelWarn, "msg")
+		l.Log(logger.LevelDebug, "msg")
+		// The wrapped logger keeps its own level
+		shared.Log(logger.LevelDebug, "msg")
+		assert.Equal(t, []logger.LogLevel{logger.LevelError, logger.LevelWarn, logger.LevelDebug}, levels)
+	})
+}
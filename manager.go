@@ -1,14 +1,24 @@
 package picodata
 
-import "github.com/picodata/picodata-go/logger"
+import (
+	"maps"
+	"time"
+
+	"github.com/picodata/picodata-go/logger"
+)
 
 type topologyManager struct {
 	provider *connectionProvider
+	// notifier is nil if nobody is interested in topology changes
+	notifier *topologyNotifier
+	logger   logger.Logger
 }
 
-func newTopologyManager(provider *connectionProvider) *topologyManager {
+func newTopologyManager(provider *connectionProvider, notifier *topologyNotifier) *topologyManager {
 	return &topologyManager{
 		provider: provider,
+		notifier: notifier,
+		logger:   provider.logger,
 	}
 }
 
@@ -16,15 +26,51 @@ func (m *topologyManager) runProcessing(eventsChan <-chan event) {
 	const op = "manager: runProcessing"
 
 	for event := range eventsChan {
-		switch event.state {
-		case stateOnline:
+		if !event.state.Known() || (event.targetState != "" && !event.targetState.Known()) {
+			logger.LogFields(m.logger, logger.LevelWarn, "unknown state, instance is excluded from routing",
+				logger.String(logger.KeyOp, op), logger.String(logger.KeyAddress, event.address),
+				logger.String(logger.KeyState, string(event.state)), logger.String("target_state", string(event.targetState)))
+		}
+
+		changed := m.provider.updateState(connState{address: event.address, currentState: event.state, targetState: event.targetState, meta: event.meta})
+		if changed {
+			m.provider.metrics.stateChanged(event.state)
+			m.notifier.publish(newTopologyEvent(TopologyEventStateChanged, event))
+		}
+
+		wasRouted := m.provider.routed(event.address)
+
+		if routable(event.state, event.targetState) && m.provider.tierAllowed(event.meta.tier) {
 			if err := m.provider.addConn(event.address); err != nil {
-				logger.Log(logger.LevelError, "%s: %v", op, err)
+				logger.LogFields(m.logger, logger.LevelError, "failed to add instance",
+					logger.String(logger.KeyOp, op), logger.String(logger.KeyAddress, event.address), logger.Err(err))
+				continue
 			}
-		case stateOffline:
-			m.provider.removeConn(event.address)
-		default:
-			logger.Log(logger.LevelWarn, "%s: undefined state %q for %q", op, event.state, event.address)
+			if !wasRouted {
+				m.notifier.publish(newTopologyEvent(TopologyEventInstanceAdded, event))
+			}
+			continue
+		}
+
+		m.provider.removeConn(event.address)
+		if wasRouted {
+			m.notifier.publish(newTopologyEvent(TopologyEventInstanceRemoved, event))
 		}
 	}
 }
+
+func newTopologyEvent(eventType TopologyEventType, e event) TopologyEvent {
+	return TopologyEvent{
+		Type:           eventType,
+		Address:        e.address,
+		RaftID:         e.meta.raftID,
+		Name:           e.meta.name,
+		ReplicasetName: e.meta.replicasetName,
+		Tier:           e.meta.tier,
+		FailureDomain:  maps.Clone(e.meta.failureDomain),
+		Leader:         e.meta.leader,
+		CurrentState:   e.state,
+		TargetState:    e.targetState,
+		Time:           time.Now(),
+	}
+}
@@ -87,13 +87,13 @@ func runManagerTestContainers(t *testing.T) {
 	eventChan := make(chan event, 10)
 
 	prov := newConnectionProvider(pool, 1)
-	manager := newTopologyManager(prov)
+	manager := newTopologyManager(prov, nil)
 	go manager.runProcessing(eventChan)
 
 	// Send events to manager
 	addrs := []string{"0.0.0.0:55433"}
 	for _, addr := range addrs {
-		eventChan <- event{address: addr, state: stateOnline}
+		eventChan <- event{address: addr, state: InstanceStateOnline}
 	}
 	close(eventChan)
 
@@ -124,13 +124,13 @@ func runManagerTestCI(t *testing.T) {
 	eventChan := make(chan event, 10)
 
 	prov := newConnectionProvider(pool, 1)
-	manager := newTopologyManager(prov)
+	manager := newTopologyManager(prov, nil)
 	go manager.runProcessing(eventChan)
 
 	// Send events to manager
 	addrs := []string{"picodata-2:5432"}
 	for _, addr := range addrs {
-		eventChan <- event{address: addr, state: stateOnline}
+		eventChan <- event{address: addr, state: InstanceStateOnline}
 	}
 	close(eventChan)
 
@@ -145,3 +145,62 @@ func runManagerTestCI(t *testing.T) {
 
 	assert.Len(t, prov.conns(), 2)
 }
+
+func TestProcessingEvents(t *testing.T) {
+	prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+	manager := newTopologyManager(prov, nil)
+
+	eventChan := make(chan event, 10)
+	events := []event{
+		{address: "127.0.0.1:5433", state: InstanceStateOnline, targetState: InstanceStateOnline},
+		// Duplicate event must not add the instance twice
+		{address: "127.0.0.1:5433", state: InstanceStateOnline, targetState: InstanceStateOnline},
+		{address: "127.0.0.1:5434", state: InstanceStateOnline, targetState: InstanceStateOnline},
+		{address: "127.0.0.1:5435", state: InstanceStateOnline},
+		// Instance that is being shut down must not receive traffic
+		{address: "127.0.0.1:5434", state: InstanceStateOnline, targetState: InstanceStateOffline},
+		{address: "127.0.0.1:5435", state: InstanceStateExpelled, targetState: InstanceStateExpelled},
+		// Instances that are still joining are not routable
+		{address: "127.0.0.1:5436", state: InstanceStateReplicated, targetState: InstanceStateOnline},
+		{address: "127.0.0.1:5437", state: "Unknown", targetState: InstanceStateOnline},
+		// Removing an unknown address must not affect other instances
+		{address: "127.0.0.1:5438", state: InstanceStateOffline, targetState: InstanceStateOffline},
+	}
+	for _, e := range events {
+		eventChan <- e
+	}
+	close(eventChan)
+
+	// runProcessing returns once the channel is drained
+	manager.runProcessing(eventChan)
+
+	conns := prov.connsMap()
+	assert.Len(t, conns, 2)
+	assert.Contains(t, conns, "127.0.0.1:5432")
+	assert.Contains(t, conns, "127.0.0.1:5433")
+}
+
+func TestProcessingEventsTiers(t *testing.T) {
+	prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+	prov.setTiers([]string{"router"})
+	defer prov.close()
+	manager := newTopologyManager(prov, nil)
+
+	eventChan := make(chan event, 10)
+	events := []event{
+		{address: "127.0.0.1:5433", state: InstanceStateOnline, targetState: InstanceStateOnline, meta: instanceMeta{tier: "router"}},
+		{address: "127.0.0.1:5434", state: InstanceStateOnline, targetState: InstanceStateOnline, meta: instanceMeta{tier: "storage"}},
+		// The initial instance is excluded once its tier is known
+		{address: "127.0.0.1:5432", state: InstanceStateOnline, targetState: InstanceStateOnline, meta: instanceMeta{tier: "storage"}},
+	}
+	for _, e := range events {
+		eventChan <- e
+	}
+	close(eventChan)
+
+	manager.runProcessing(eventChan)
+
+	conns := prov.connsMap()
+	assert.Len(t, conns, 1)
+	assert.Contains(t, conns, "127.0.0.1:5433")
+}
@@ -0,0 +1,107 @@
+package picodata
+
+import (
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics is a snapshot of the pool counters, see [Pool.Metrics].
+// All counters are cumulative since the pool was created.
+type Metrics struct {
+	// QueriesRouted is the number of operation attempts routed to each instance, keyed by instance address.
+	// Removed instances are kept, so the counters never go back.
+	QueriesRouted map[string]uint64
+	// Retries is the number of operations repeated on another instance after a failure.
+	Retries uint64
+	// TopologyPolls is the number of topology polls, including failed ones.
+	TopologyPolls uint64
+	// TopologyPollFailures is the number of failed topology polls.
+	TopologyPollFailures uint64
+	// TopologyPollDuration is the total time spent on topology polls.
+	TopologyPollDuration time.Duration
+	// StateTransitions is the number of instance state changes processed by the topology manager,
+	// keyed by the new current state.
+	StateTransitions map[InstanceState]uint64
+}
+
+// Metrics returns a snapshot of the pool counters.
+// Use it together with [Pool.Topology] to export the pool state, see the metrics package.
+func (p *Pool) Metrics() Metrics {
+	return p.provider.metrics.snapshot()
+}
+
+// poolMetrics collects the pool counters. It is shared by all pool components through the provider.
+type poolMetrics struct {
+	retries              atomic.Uint64
+	topologyPolls        atomic.Uint64
+	topologyPollFailures atomic.Uint64
+	topologyPollDuration atomic.Int64
+
+	mu sync.RWMutex
+	// Key: instance address
+	queriesRouted    map[string]*atomic.Uint64
+	stateTransitions map[InstanceState]uint64
+}
+
+func newPoolMetrics() *poolMetrics {
+	return &poolMetrics{
+		queriesRouted:    make(map[string]*atomic.Uint64),
+		stateTransitions: make(map[InstanceState]uint64),
+	}
+}
+
+func (m *poolMetrics) queryRouted(address string) {
+	m.mu.RLock()
+	counter, ok := m.queriesRouted[address]
+	m.mu.RUnlock()
+
+	if !ok {
+		m.mu.Lock()
+		if counter, ok = m.queriesRouted[address]; !ok {
+			counter = &atomic.Uint64{}
+			m.queriesRouted[address] = counter
+		}
+		m.mu.Unlock()
+	}
+
+	counter.Add(1)
+}
+
+func (m *poolMetrics) retried() {
+	m.retries.Add(1)
+}
+
+func (m *poolMetrics) topologyPolled(duration time.Duration, err error) {
+	m.topologyPolls.Add(1)
+	m.topologyPollDuration.Add(int64(duration))
+	if err != nil {
+		m.topologyPollFailures.Add(1)
+	}
+}
+
+func (m *poolMetrics) stateChanged(state InstanceState) {
+	m.mu.Lock()
+	m.stateTransitions[state]++
+	m.mu.Unlock()
+}
+
+func (m *poolMetrics) snapshot() Metrics {
+	m.mu.RLock()
+	queriesRouted := make(map[string]uint64, len(m.queriesRouted))
+	for address, counter := range m.queriesRouted {
+		queriesRouted[address] = counter.Load()
+	}
+	stateTransitions := maps.Clone(m.stateTransitions)
+	m.mu.RUnlock()
+
+	return Metrics{
+		QueriesRouted:        queriesRouted,
+		Retries:              m.retries.Load(),
+		TopologyPolls:        m.topologyPolls.Load(),
+		TopologyPollFailures: m.topologyPollFailures.Load(),
+		TopologyPollDuration: time.Duration(m.topologyPollDuration.Load()),
+		StateTransitions:     stateTransitions,
+	}
+}
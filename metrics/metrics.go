@@ -0,0 +1,389 @@
+// Package metrics exports picodata.Pool statistics in the OpenMetrics text format,
+// which Prometheus scrapes natively. It has no dependencies besides the standard library.
+//
+//	registry := metrics.NewRegistry()
+//	if err := registry.Register("main", pool); err != nil {
+//		return err
+//	}
+//	http.Handle("/metrics", registry)
+//
+// Exported metric families:
+//
+//	picodata_pool_instance_routed                         gauge    1 if operations are routed to the instance
+//	picodata_pool_instance_circuit_state                  gauge    0 - closed, 1 - open, 2 - half-open
+//	picodata_pool_instance_acquired_conns                 gauge
+//	picodata_pool_instance_idle_conns                     gauge
+//	picodata_pool_instance_total_conns                    gauge
+//	picodata_pool_instance_max_conns                      gauge
+//	picodata_pool_instance_acquires_total                 counter
+//	picodata_pool_instance_acquire_duration_seconds_total counter
+//	picodata_pool_instance_empty_acquires_total           counter
+//	picodata_pool_instance_canceled_acquires_total        counter
+//	picodata_pool_instance_queries_routed_total           counter
+//	picodata_pool_topology_generation                     gauge
+//	picodata_pool_topology_polls_total                    counter
+//	picodata_pool_topology_poll_failures_total            counter
+//	picodata_pool_topology_poll_duration_seconds          summary  only _count and _sum
+//	picodata_pool_state_transitions_total                 counter  labeled by the new state
+//	picodata_pool_retries_total                           counter
+//
+// Every sample is labeled with the name the pool was registered with,
+// instance metrics are labeled with the instance address as well.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	picodata "github.com/picodata/picodata-go"
+)
+
+// ContentType is the content type of the exposition format written by [Registry].
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+const namespace = "picodata_pool"
+
+var _ Source = (*picodata.Pool)(nil)
+
+// Source provides metrics of a single pool. It is implemented by *picodata.Pool.
+type Source interface {
+	Topology() picodata.Topology
+	Metrics() picodata.Metrics
+}
+
+// Registry collects metrics of registered pools and serves them over HTTP.
+type Registry struct {
+	mu sync.RWMutex
+	// Key: pool name
+	pools map[string]Source
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		pools: make(map[string]Source),
+	}
+}
+
+// Register adds pool to the registry. name is used as the value of the pool label.
+func (r *Registry) Register(name string, pool Source) error {
+	const op = "metrics: Register"
+
+	if pool == nil {
+		return fmt.Errorf("%s: pool is nil", op)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.pools[name]; ok {
+		return fmt.Errorf("%s: pool %q is already registered", op, name)
+	}
+	r.pools[name] = pool
+
+	return nil
+}
+
+// Unregister removes the pool registered with name. It should be called before the pool is closed.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.pools, name)
+	r.mu.Unlock()
+}
+
+// ServeHTTP implements http.Handler, it writes metrics of all registered pools.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_, _ = r.WriteTo(w)
+}
+
+// WriteTo writes metrics of all registered pools to w in the OpenMetrics text format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.pools))
+	for name := range r.pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	snapshots := make([]poolSnapshot, 0, len(names))
+	for _, name := range names {
+		pool := r.pools[name]
+		snapshots = append(snapshots, poolSnapshot{
+			name:     name,
+			topology: pool.Topology(),
+			metrics:  pool.Metrics(),
+		})
+	}
+	r.mu.RUnlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	for _, family := range families {
+		family.write(cw, snapshots)
+	}
+	cw.printf("# EOF\n")
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+
+	return cw.n, cw.err
+}
+
+type poolSnapshot struct {
+	name     string
+	topology picodata.Topology
+	metrics  picodata.Metrics
+}
+
+type sample struct {
+	// suffix is appended to the family name, e.g. _count and _sum of a summary
+	suffix string
+	labels [][2]string
+	value  float64
+}
+
+// family is a metric family. Samples are produced for every registered pool.
+type family struct {
+	name       string
+	metricType string
+	help       string
+	samples    func(s poolSnapshot) []sample
+}
+
+func (f family) write(w *countingWriter, snapshots []poolSnapshot) {
+	w.printf("# TYPE %s_%s %s\n", namespace, f.name, f.metricType)
+	w.printf("# HELP %s_%s %s\n", namespace, f.name, f.help)
+
+	suffix := ""
+	if f.metricType == "counter" {
+		suffix = "_total"
+	}
+
+	for _, s := range snapshots {
+		for _, smp := range f.samples(s) {
+			labels := append([][2]string{{"pool", s.name}}, smp.labels...)
+			w.printf("%s_%s%s%s{%s} %s\n", namespace, f.name, suffix, smp.suffix, formatLabels(labels), formatValue(smp.value))
+		}
+	}
+}
+
+// instanceSamples builds a sample for every known instance value reports ok for.
+func instanceSamples(s poolSnapshot, value func(inst picodata.InstanceInfo) (float64, bool)) []sample {
+	samples := make([]sample, 0, len(s.topology.Instances))
+	for _, inst := range s.topology.Instances {
+		if v, ok := value(inst); ok {
+			samples = append(samples, sample{labels: [][2]string{{"instance", inst.Address}}, value: v})
+		}
+	}
+	return samples
+}
+
+// statSamples builds samples from pool statistics of routed instances.
+func statSamples(s poolSnapshot, value func(inst picodata.InstanceInfo) float64) []sample {
+	return instanceSamples(s, func(inst picodata.InstanceInfo) (float64, bool) {
+		if inst.Stat == nil {
+			return 0, false
+		}
+		return value(inst), true
+	})
+}
+
+var families = []family{
+	{
+		name: "instance_routed", metricType: "gauge",
+		help: "Whether operations are routed to the instance.",
+		samples: func(s poolSnapshot) []sample {
+			return instanceSamples(s, func(inst picodata.InstanceInfo) (float64, bool) {
+				if inst.Routed {
+					return 1, true
+				}
+				return 0, true
+			})
+		},
+	},
+	{
+		name: "instance_circuit_state", metricType: "gauge",
+		help: "Circuit breaker state of the instance: 0 - closed, 1 - open, 2 - half-open.",
+		samples: func(s poolSnapshot) []sample {
+			return instanceSamples(s, func(inst picodata.InstanceInfo) (float64, bool) {
+				return float64(inst.CircuitState), inst.Routed
+			})
+		},
+	},
+	{
+		name: "instance_acquired_conns", metricType: "gauge",
+		help: "Number of currently acquired connections to the instance.",
+		samples: func(s poolSnapshot) []sample {
+			return statSamples(s, func(inst picodata.InstanceInfo) float64 { return float64(inst.Stat.AcquiredConns()) })
+		},
+	},
+	{
+		name: "instance_idle_conns", metricType: "gauge",
+		help: "Number of currently idle connections to the instance.",
+		samples: func(s poolSnapshot) []sample {
+			return statSamples(s, func(inst picodata.InstanceInfo) float64 { return float64(inst.Stat.IdleConns()) })
+		},
+	},
+	{
+		name: "instance_total_conns", metricType: "gauge",
+		help: "Total number of connections to the instance.",
+		samples: func(s poolSnapshot) []sample {
+			return statSamples(s, func(inst picodata.InstanceInfo) float64 { return float64(inst.Stat.TotalConns()) })
+		},
+	},
+	{
+		name: "instance_max_conns", metricType: "gauge",
+		help: "Maximum number of connections to the instance.",
+		samples: func(s poolSnapshot) []sample {
+			return statSamples(s, func(inst picodata.InstanceInfo) float64 { return float64(inst.Stat.MaxConns()) })
+		},
+	},
+	{
+		name: "instance_acquires", metricType: "counter",
+		help: "Number of successful connection acquires from the instance pool.",
+		samples: func(s poolSnapshot) []sample {
+			return statSamples(s, func(inst picodata.InstanceInfo) float64 { return float64(inst.Stat.AcquireCount()) })
+		},
+	},
+	{
+		name: "instance_acquire_duration_seconds", metricType: "counter",
+		help: "Total duration of successful connection acquires from the instance pool.",
+		samples: func(s poolSnapshot) []sample {
+			return statSamples(s, func(inst picodata.InstanceInfo) float64 { return inst.Stat.AcquireDuration().Seconds() })
+		},
+	},
+	{
+		name: "instance_empty_acquires", metricType: "counter",
+		help: "Number of acquires that waited for a connection because the instance pool was empty.",
+		samples: func(s poolSnapshot) []sample {
+			return statSamples(s, func(inst picodata.InstanceInfo) float64 { return float64(inst.Stat.EmptyAcquireCount()) })
+		},
+	},
+	{
+		name: "instance_canceled_acquires", metricType: "counter",
+		help: "Number of acquires from the instance pool canceled by a context.",
+		samples: func(s poolSnapshot) []sample {
+			return statSamples(s, func(inst picodata.InstanceInfo) float64 { return float64(inst.Stat.CanceledAcquireCount()) })
+		},
+	},
+	{
+		name: "instance_queries_routed", metricType: "counter",
+		help: "Number of operation attempts routed to the instance by the balance strategy.",
+		samples: func(s poolSnapshot) []sample {
+			addresses := make([]string, 0, len(s.metrics.QueriesRouted))
+			for address := range s.metrics.QueriesRouted {
+				addresses = append(addresses, address)
+			}
+			sort.Strings(addresses)
+
+			samples := make([]sample, 0, len(addresses))
+			for _, address := range addresses {
+				samples = append(samples, sample{
+					labels: [][2]string{{"instance", address}},
+					value:  float64(s.metrics.QueriesRouted[address]),
+				})
+			}
+			return samples
+		},
+	},
+	{
+		name: "topology_generation", metricType: "gauge",
+		help: "Number of times an instance was added to or removed from routing.",
+		samples: func(s poolSnapshot) []sample {
+			return []sample{{value: float64(s.topology.Generation)}}
+		},
+	},
+	{
+		name: "topology_polls", metricType: "counter",
+		help: "Number of topology polls, including failed ones.",
+		samples: func(s poolSnapshot) []sample {
+			return []sample{{value: float64(s.metrics.TopologyPolls)}}
+		},
+	},
+	{
+		name: "topology_poll_failures", metricType: "counter",
+		help: "Number of failed topology polls.",
+		samples: func(s poolSnapshot) []sample {
+			return []sample{{value: float64(s.metrics.TopologyPollFailures)}}
+		},
+	},
+	{
+		name: "topology_poll_duration_seconds", metricType: "summary",
+		help: "Duration of topology polls.",
+		samples: func(s poolSnapshot) []sample {
+			return []sample{
+				{suffix: "_count", value: float64(s.metrics.TopologyPolls)},
+				{suffix: "_sum", value: s.metrics.TopologyPollDuration.Seconds()},
+			}
+		},
+	},
+	{
+		name: "state_transitions", metricType: "counter",
+		help: "Number of instance state changes processed by the topology manager, labeled by the new state.",
+		samples: func(s poolSnapshot) []sample {
+			states := make([]string, 0, len(s.metrics.StateTransitions))
+			for state := range s.metrics.StateTransitions {
+				states = append(states, string(state))
+			}
+			sort.Strings(states)
+
+			samples := make([]sample, 0, len(states))
+			for _, state := range states {
+				samples = append(samples, sample{
+					labels: [][2]string{{"state", state}},
+					value:  float64(s.metrics.StateTransitions[picodata.InstanceState(state)]),
+				})
+			}
+			return samples
+		},
+	},
+	{
+		name: "retries", metricType: "counter",
+		help: "Number of operations repeated on another instance after a failure.",
+		samples: func(s poolSnapshot) []sample {
+			return []sample{{value: float64(s.metrics.Retries)}}
+		},
+	},
+}
+
+func formatLabels(labels [][2]string) string {
+	var b strings.Builder
+	for i, label := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(label[0])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(label[1]))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// countingWriter remembers the first error, so families can be written without checking every write.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) printf(format string, args ...any) {
+	if cw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(cw.w, format, args...)
+	cw.n += int64(n)
+	cw.err = err
+}
@@ -0,0 +1,115 @@
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	picodata "github.com/picodata/picodata-go"
+	"github.com/picodata/picodata-go/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	topology picodata.Topology
+	metrics  picodata.Metrics
+}
+
+func (s fakeSource) Topology() picodata.Topology {
+	return s.topology
+}
+
+func (s fakeSource) Metrics() picodata.Metrics {
+	return s.metrics
+}
+
+func newStat(t *testing.T, maxConns int32) *pgxpool.Stat {
+	cfg, err := pgxpool.ParseConfig("host=127.0.0.1 port=1")
+	require.NoError(t, err)
+	cfg.MaxConns = maxConns
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	return pool.Stat()
+}
+
+func TestRegistry(t *testing.T) {
+	source := fakeSource{
+		topology: picodata.Topology{
+			Generation: 3,
+			Instances: []picodata.InstanceInfo{
+				{Address: "127.0.0.1:5432", Routed: true, Stat: newStat(t, 4), CircuitState: picodata.CircuitOpen},
+				{Address: "127.0.0.1:5433"},
+			},
+		},
+		metrics: picodata.Metrics{
+			QueriesRouted:        map[string]uint64{"127.0.0.1:5432": 10, "127.0.0.1:5433": 5},
+			Retries:              2,
+			TopologyPolls:        4,
+			TopologyPollFailures: 1,
+			TopologyPollDuration: 1500 * time.Millisecond,
+			StateTransitions:     map[picodata.InstanceState]uint64{picodata.InstanceStateOnline: 2, picodata.InstanceStateOffline: 1},
+		},
+	}
+
+	registry := metrics.NewRegistry()
+	require.NoError(t, registry.Register("main", source))
+	assert.Error(t, registry.Register("main", source))
+
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, metrics.ContentType, resp.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	text := string(body)
+
+	for _, line := range []string{
+		"# TYPE picodata_pool_instance_routed gauge",
+		`picodata_pool_instance_routed{pool="main",instance="127.0.0.1:5432"} 1`,
+		`picodata_pool_instance_routed{pool="main",instance="127.0.0.1:5433"} 0`,
+		`picodata_pool_instance_circuit_state{pool="main",instance="127.0.0.1:5432"} 1`,
+		`picodata_pool_instance_max_conns{pool="main",instance="127.0.0.1:5432"} 4`,
+		"# TYPE picodata_pool_instance_queries_routed counter",
+		`picodata_pool_instance_queries_routed_total{pool="main",instance="127.0.0.1:5432"} 10`,
+		`picodata_pool_instance_queries_routed_total{pool="main",instance="127.0.0.1:5433"} 5`,
+		`picodata_pool_topology_generation{pool="main"} 3`,
+		`picodata_pool_topology_polls_total{pool="main"} 4`,
+		`picodata_pool_topology_poll_failures_total{pool="main"} 1`,
+		`picodata_pool_topology_poll_duration_seconds_count{pool="main"} 4`,
+		`picodata_pool_topology_poll_duration_seconds_sum{pool="main"} 1.5`,
+		`picodata_pool_state_transitions_total{pool="main",state="Offline"} 1`,
+		`picodata_pool_state_transitions_total{pool="main",state="Online"} 2`,
+		`picodata_pool_retries_total{pool="main"} 2`,
+	} {
+		assert.Contains(t, text, line+"\n")
+	}
+	assert.True(t, strings.HasSuffix(text, "# EOF\n"))
+
+	// Stats of instances that aren't routed are not exported
+	assert.NotContains(t, text, `picodata_pool_instance_max_conns{pool="main",instance="127.0.0.1:5433"}`)
+
+	registry.Unregister("main")
+	_, err = registry.WriteTo(io.Discard)
+	require.NoError(t, err)
+}
+
+func TestLabelEscaping(t *testing.T) {
+	registry := metrics.NewRegistry()
+	require.NoError(t, registry.Register("a\"b\\c\nd", fakeSource{}))
+
+	rec := httptest.NewRecorder()
+	registry.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Contains(t, rec.Body.String(), `picodata_pool_retries_total{pool="a\"b\\c\nd"} 0`)
+}
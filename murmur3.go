@@ -0,0 +1,54 @@
+package picodata
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	murmur3C1 = 0xcc9e2d51
+	murmur3C2 = 0x1b873593
+)
+
+// murmur3 returns the 32-bit x86 MurmurHash3 of data with zero seed,
+// which is the hash Picodata computes bucket ids with.
+func murmur3(data []byte) uint32 {
+	var h uint32
+	length := len(data)
+
+	for ; len(data) >= 4; data = data[4:] {
+		k := binary.LittleEndian.Uint32(data)
+		k *= murmur3C1
+		k = bits.RotateLeft32(k, 15)
+		k *= murmur3C2
+
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	switch len(data) {
+	case 3:
+		k ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(data[0])
+		k *= murmur3C1
+		k = bits.RotateLeft32(k, 15)
+		k *= murmur3C2
+		h ^= k
+	}
+
+	h ^= uint32(length)
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+
+	return h
+}
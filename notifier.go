@@ -0,0 +1,125 @@
+package picodata
+
+import (
+	"context"
+	"sync"
+
+	"github.com/picodata/picodata-go/logger"
+)
+
+// defaultSubscriberBufferSize is the number of topology events a subscriber may lag behind before events are dropped.
+const defaultSubscriberBufferSize = 64
+
+// topologyNotifier delivers topology changes to hooks and subscribers.
+type topologyNotifier struct {
+	hooks  TopologyHooks
+	logger logger.Logger
+
+	mu          sync.Mutex
+	subscribers map[chan TopologyEvent]struct{}
+	// done is closed by close, so subscriptions don't outlive the notifier
+	done chan struct{}
+}
+
+func newTopologyNotifier(hooks TopologyHooks, l logger.Logger) *topologyNotifier {
+	return &topologyNotifier{
+		hooks:       hooks,
+		logger:      l,
+		subscribers: make(map[chan TopologyEvent]struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+func (n *topologyNotifier) subscribe(ctx context.Context) <-chan TopologyEvent {
+	ch := make(chan TopologyEvent, defaultSubscriberBufferSize)
+
+	n.mu.Lock()
+	if n.isClosed() {
+		n.mu.Unlock()
+		close(ch)
+		return ch
+	}
+	n.subscribers[ch] = struct{}{}
+	n.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			n.unsubscribe(ch)
+		case <-n.done:
+			// close has already closed ch
+		}
+	}()
+
+	return ch
+}
+
+func (n *topologyNotifier) unsubscribe(ch chan TopologyEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.subscribers[ch]; !ok {
+		return
+	}
+	delete(n.subscribers, ch)
+	close(ch)
+}
+
+// publish calls hooks and sends the event to subscribers without blocking. It is safe to call on nil notifier.
+func (n *topologyNotifier) publish(event TopologyEvent) {
+	const op = "notifier: publish"
+
+	if n == nil {
+		return
+	}
+
+	var hook func(TopologyEvent)
+	switch event.Type {
+	case TopologyEventInstanceAdded:
+		hook = n.hooks.OnInstanceAdded
+	case TopologyEventInstanceRemoved:
+		hook = n.hooks.OnInstanceRemoved
+	case TopologyEventStateChanged:
+		hook = n.hooks.OnStateChanged
+	}
+	if hook != nil {
+		hook(event)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for ch := range n.subscribers {
+		select {
+		case ch <- event:
+		default:
+			logger.LogFields(n.logger, logger.LevelWarn, "subscriber is full, event is dropped",
+				logger.String(logger.KeyOp, op), logger.String(logger.KeyAddress, event.Address), logger.String("event", event.Type.String()))
+		}
+	}
+}
+
+// isClosed reports whether close was called. Must be called with mu held.
+func (n *topologyNotifier) isClosed() bool {
+	select {
+	case <-n.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// close closes all subscriber channels, it is called when the pool is closed.
+func (n *topologyNotifier) close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.isClosed() {
+		return
+	}
+	close(n.done)
+	for ch := range n.subscribers {
+		delete(n.subscribers, ch)
+		close(ch)
+	}
+}
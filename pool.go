@@ -4,19 +4,32 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/picodata/picodata-go/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Pool allows for connection reuse.
 // It operates with slice of *pgxpool.Pool, each Picodata instance have its own pool object.
+//
+// If there are no instances to route an operation to, Pool methods return [ErrNoAvailableInstances],
+// optionally after waiting for an instance to come back online (see [WithInstanceWaitTimeout]).
 type Pool struct {
-	provider *connectionProvider
-	manager  *topologyManager
-	producer *stateProducer
+	provider    *connectionProvider
+	manager     *topologyManager
+	producer    *stateProducer
+	retryPolicy RetryPolicy
+	notifier    *topologyNotifier
+	// tracer is nil if tracing is disabled
+	tracer trace.Tracer
+	// routingMode is nil if operations are routed according to their kind, see [WithDefaultRoutingMode]
+	routingMode *RoutingMode
+	router      *shardingRouter
 
 	stopOnce sync.Once
 	stopChan chan struct{}
@@ -44,21 +57,16 @@ func New(ctx context.Context, connString string, opts ...PoolOption) (*Pool, err
 func NewWithConfig(ctx context.Context, config *pgxpool.Config, opts ...PoolOption) (*Pool, error) {
 	const op = "pool: NewWithConfig"
 
-	poolOpts := &poolOpts{}
+	poolOpts := &poolOpts{
+		producerConfig:  defaultProducerConfig(),
+		eventBufferSize: defaultEventBufferSize,
+	}
 	for i, opt := range opts {
 		if err := opt(poolOpts); err != nil {
 			return nil, fmt.Errorf("%s: applying option %d: %w", op, i+1, err)
 		}
 	}
 
-	if poolOpts.logger != nil {
-		logger.SetDefaultLogger(poolOpts.logger)
-	}
-
-	if poolOpts.logLevel > 0 {
-		logger.SetLevel(poolOpts.logLevel)
-	}
-
 	initConn, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
@@ -67,22 +75,41 @@ func NewWithConfig(ctx context.Context, config *pgxpool.Config, opts ...PoolOpti
 	stopChan := make(chan struct{})
 	var connPool *Pool
 	provider := newConnectionProvider(initConn, poolOpts.maxConnsPerInstance)
-	if poolOpts.balanceStrategy != nil {
-		provider.setBalanceStrategy(poolOpts.balanceStrategy)
+	if l := poolOpts.poolLogger(); l != nil {
+		provider.setLogger(l)
+	}
+	if strategy := poolOpts.instanceStrategy(); strategy != nil {
+		provider.setStrategy(strategy)
+	}
+	if poolOpts.instanceWaitTimeout > 0 {
+		provider.setInstanceWaitTimeout(poolOpts.instanceWaitTimeout)
+	}
+	if poolOpts.drainTimeout != nil {
+		provider.setDrainTimeout(*poolOpts.drainTimeout)
+	}
+	if poolOpts.circuitBreaker != nil {
+		provider.setCircuitBreaker(*poolOpts.circuitBreaker)
+	}
+	if poolOpts.tiers != nil {
+		provider.setTiers(poolOpts.tiers)
+	}
+	if poolOpts.tierMaxConns != nil {
+		provider.setTierMaxConns(poolOpts.tierMaxConns)
 	}
 
 	if err := initialDiscovery(ctx, provider); err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	notifier := newTopologyNotifier(poolOpts.topologyHooks, provider.logger)
+
 	var manager *topologyManager
 	var producer *stateProducer
 	if !poolOpts.disableTopologyManager {
-		//TODO: research suitable capacity for eventChan
-		eventChan := make(chan event, 10)
-		manager = newTopologyManager(provider)
+		eventChan := make(chan event, poolOpts.eventBufferSize)
+		manager = newTopologyManager(provider, notifier)
 
-		producer, err = newStateProducer(provider, poolOpts.serviceConnAddress)
+		producer, err = newStateProducer(provider, poolOpts.serviceConnAddress, poolOpts.producerConfig)
 		if err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
@@ -90,7 +117,17 @@ func NewWithConfig(ctx context.Context, config *pgxpool.Config, opts ...PoolOpti
 		go producer.runProducing(eventChan, stopChan)
 	}
 
-	connPool = &Pool{provider: provider, manager: manager, producer: producer, stopChan: stopChan}
+	connPool = &Pool{
+		provider:    provider,
+		manager:     manager,
+		producer:    producer,
+		retryPolicy: poolOpts.retryPolicy,
+		notifier:    notifier,
+		tracer:      poolOpts.tracer,
+		routingMode: poolOpts.routingMode,
+		router:      newShardingRouter(provider, poolOpts.bucketResolver),
+		stopChan:    stopChan,
+	}
 
 	return connPool, nil
 }
@@ -100,6 +137,19 @@ func (p *Pool) Config() *pgxpool.Config {
 	return p.provider.config()
 }
 
+// InstancePool returns the *pgxpool.Pool of the instance chosen by the balance strategy.
+// It is intended for integrations, such as the stdlib package, that have to work with pgxpool directly.
+// The returned pool is owned by p and must not be closed by the caller.
+func (p *Pool) InstancePool(ctx context.Context) (*pgxpool.Pool, error) {
+	inst, err := p.provider.nextConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.provider.metrics.queryRouted(inst.address)
+
+	return inst.pool, nil
+}
+
 // Ping acquires a connection from the Pool and executes a simple SQL statement against it.
 // If the sql returns without error, the database Ping is considered successful, otherwise, the error is returned.
 func (p *Pool) Ping(ctx context.Context) error {
@@ -108,7 +158,12 @@ func (p *Pool) Ping(ctx context.Context) error {
 	// The original *pgxpool.Ping() method sends an empty query, **--ping**, which is a comment.
 	// We need to use a custom function to send a simple **SELECT 1** query instead.
 	// Replace to original Ping method when comment support is implemented.
-	for _, pool := range p.provider.conns() {
+	pools := p.provider.conns()
+	if len(pools) == 0 {
+		return ErrNoAvailableInstances
+	}
+
+	for _, pool := range pools {
 		if err := pingPool(ctx, pool); err != nil {
 			return err
 		}
@@ -127,9 +182,21 @@ func (p *Pool) Ping(ctx context.Context) error {
 // For extra control over how the query is executed, the types QuerySimpleProtocol, QueryResultFormats, and
 // QueryResultFormatsByOID may be used as the first args to control exactly how the query is executed. This is rarely
 // needed. See the documentation for those types for details.
+//
+// Query is routed to any instance unless the routing mode is set, see [WithRoutingMode].
+// Its span ends when the rows are read or closed.
 func (p *Pool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
-	conn := p.provider.nextConnection()
-	return conn.Query(ctx, sql, args...)
+	var rows pgx.Rows
+	span, err := p.withRetrySpan(p.routingContext(ctx, RoutingModeAny), spanQuery, isIdempotent(ctx), func(ctx context.Context, inst *instance) error {
+		var err error
+		rows, err = inst.pool.Query(ctx, sql, args...)
+		return err
+	})
+	if err != nil {
+		return errRows{err: err}, err
+	}
+
+	return newTracedRows(rows, span), nil
 }
 
 // QueryRow acquires a connection and executes a query that is expected
@@ -145,8 +212,10 @@ func (p *Pool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, er
 // QueryResultFormatsByOID may be used as the first args to control exactly how the query is executed. This is rarely
 // needed. See the documentation for those types for details.
 func (p *Pool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
-	conn := p.provider.nextConnection()
-	return conn.QueryRow(ctx, sql, args...)
+	// NOTE: QueryRow is built on top of Query, so the query is retried
+	// before the row is handed out instead of failing on Scan.
+	rows, err := p.Query(ctx, sql, args...)
+	return &poolRow{rows: rows, err: err}
 }
 
 // SendBatch acquires a connection from the pool and sends a batch of SQL
@@ -159,7 +228,7 @@ func (p *Pool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
 // pgx.BatchResults that will return the acquisition error on any result method.
 //
 // The caller must ensure that the returned BatchResults is closed via
-// Close() to release the connection back to the pool.
+// Close() to release the connection back to the pool. Its span ends then as well.
 //
 // Example usage
 //
@@ -173,29 +242,64 @@ func (p *Pool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
 //	err := results.QueryRow().Scan(&count)
 //	if err != nil{...}
 func (p *Pool) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
-	conn := p.provider.nextConnection()
-	return conn.SendBatch(ctx, b)
+	// NOTE: batch is not idempotent, so only acquiring a connection is retried.
+	var results *poolBatchResults
+	span, err := p.withRetrySpan(p.routingContext(ctx, RoutingModePreferLeader), spanSendBatch, true, func(ctx context.Context, inst *instance) error {
+		conn, err := inst.pool.Acquire(ctx)
+		if err != nil {
+			return err
+		}
+		results = &poolBatchResults{br: conn.SendBatch(ctx, b), conn: conn}
+		return nil
+	})
+	if err != nil {
+		return errBatchResults{err: err}
+	}
+	results.span = span
+
+	return results
 }
 
 // Exec acquires a connection from the Pool and executes the given SQL.
 // SQL can be either a prepared statement name or an SQL string.
 // Arguments should be referenced positionally from the SQL string as $1, $2, etc.
 // The acquired connection is returned to the pool when the Exec function returns.
+//
+// Exec is routed to replicaset leaders when they are available unless the routing mode is set,
+// see [WithRoutingMode].
 func (p *Pool) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
-	conn := p.provider.nextConnection()
-	return conn.Exec(ctx, sql, args...)
+	var tag pgconn.CommandTag
+	err := p.withRetry(p.routingContext(ctx, RoutingModePreferLeader), spanExec, isIdempotent(ctx), func(ctx context.Context, inst *instance) error {
+		var err error
+		tag, err = inst.pool.Exec(ctx, sql, args...)
+		return err
+	})
+
+	return tag, err
+}
+
+// CopyFrom acquires a connection from the Pool and uses it to perform a copy protocol
+// operation on the chosen instance. See pgx.Conn.CopyFrom for details.
+func (p *Pool) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	var n int64
+	err := p.withRetry(p.routingContext(ctx, RoutingModePreferLeader), spanCopyFrom, false, func(ctx context.Context, inst *instance) error {
+		var err error
+		n, err = inst.pool.CopyFrom(ctx, tableName, columnNames, rowSrc)
+		return err
+	})
+
+	return n, err
 }
 
-// Close closes all connections in the pool and rejects future Acquire calls. Blocks until all connections are returned
-// to pool and closed.
+// Close closes all connections in the pool, including pools of removed instances that are still draining,
+// and rejects future Acquire calls. Blocks until all connections are returned to pool and closed.
 //
 // It is safe to close a pool multiple times.
 func (p *Pool) Close() {
 	p.stopOnce.Do(func() {
 		close(p.stopChan)
-		for _, pool := range p.provider.conns() {
-			pool.Close()
-		}
+		p.provider.close()
+		p.notifier.close()
 	})
 }
 
@@ -225,3 +329,79 @@ func pingPool(ctx context.Context, p *pgxpool.Pool) error {
 
 	return nil
 }
+
+// withRetry calls fn with the instance chosen by the balance strategy. If fn fails and
+// the retry policy allows it, fn is called again with another instance.
+// Non-idempotent operations are retried only if nothing was sent to the instance.
+// All attempts are traced as a single span named spanName, fn is called with its context.
+func (p *Pool) withRetry(ctx context.Context, spanName string, idempotent bool, fn func(ctx context.Context, inst *instance) error) error {
+	span, err := p.withRetrySpan(ctx, spanName, idempotent, fn)
+	if err != nil {
+		return err
+	}
+	span.End()
+
+	return nil
+}
+
+// withRetrySpan is the same as withRetry, but the span is left open if fn succeeds,
+// so it can be ended once the result of the operation, such as rows or a transaction, is done with.
+func (p *Pool) withRetrySpan(ctx context.Context, spanName string, idempotent bool, fn func(ctx context.Context, inst *instance) error) (trace.Span, error) {
+	ctx, span := p.startSpan(ctx, spanName)
+	if err := p.retry(ctx, span, idempotent, fn); err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+
+	return span, nil
+}
+
+func (p *Pool) retry(ctx context.Context, span trace.Span, idempotent bool, fn func(ctx context.Context, inst *instance) error) error {
+	const op = "pool: retry"
+
+	var failed *instance
+	for attempt := 1; ; attempt++ {
+		inst, err := p.provider.nextConnectionExcept(ctx, failed)
+		if err != nil {
+			return err
+		}
+
+		p.provider.metrics.queryRouted(inst.address)
+		p.traceAttempt(span, inst, attempt)
+		start := time.Now()
+		err = fn(ctx, inst)
+		p.provider.observe(inst, time.Since(start), err)
+		inst.reportResult(err)
+		if err == nil {
+			return nil
+		}
+
+		if p.retryPolicy == nil || !retryAllowed(ctx) || (!idempotent && !isSafeToRetry(err)) {
+			return err
+		}
+
+		delay, ok := p.retryPolicy.Retry(attempt, err)
+		if !ok {
+			return err
+		}
+
+		logger.LogFields(p.provider.logger, logger.LevelDebug, "attempt failed, retrying on another instance",
+			logger.String(logger.KeyOp, op), logger.String(logger.KeyAddress, inst.address),
+			logger.Any("attempt", attempt), logger.Any("delay", delay), logger.Err(err))
+		span.AddEvent("retry", trace.WithAttributes(
+			attrInstanceAddress.String(inst.address),
+			attrRetryAttempt.Int(attempt),
+			attribute.String("error", err.Error()),
+		))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		p.provider.metrics.retried()
+		failed = inst
+	}
+}
@@ -2,18 +2,35 @@ package picodata
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/picodata/picodata-go/logger"
 	"github.com/picodata/picodata-go/strategies"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type poolOpts struct {
-	logLevel               logger.LogLevel
+	logLevel               *logger.LogLevel
 	logger                 logger.Logger
-	balanceStrategy        strategies.BalanceStrategy
+	strategy               strategies.InstanceStrategy
 	serviceConnAddress     string
 	disableTopologyManager bool
 	maxConnsPerInstance    int32
+	instanceWaitTimeout    time.Duration
+	retryPolicy            RetryPolicy
+	circuitBreaker         *CircuitBreakerConfig
+	producerConfig         producerConfig
+	eventBufferSize        int
+	drainTimeout           *time.Duration
+	topologyHooks          TopologyHooks
+	tracer                 trace.Tracer
+	localFailureDomain     map[string]string
+	tiers                  []string
+	tierMaxConns           map[string]int32
+	routingMode            *RoutingMode
+	bucketResolver         BucketResolver
 }
 
 type PoolOption func(*poolOpts) error
@@ -24,12 +41,53 @@ func WithBalanceStrategy(strategy strategies.BalanceStrategy) PoolOption {
 		if strategy == nil {
 			return fmt.Errorf("balance strategy is nil")
 		}
-		p.balanceStrategy = strategy
+		p.strategy = strategies.FromBalanceStrategy(strategy)
 		return nil
 	}
 }
 
-// WithLogger sets a custom pool internal logger to print information
+// WithInstanceStrategy defines logic of pool balancing with a strategy
+// that sees metadata and load of every candidate instance. It replaces WithBalanceStrategy.
+func WithInstanceStrategy(strategy strategies.InstanceStrategy) PoolOption {
+	return func(p *poolOpts) error {
+		if strategy == nil {
+			return fmt.Errorf("instance strategy is nil")
+		}
+		p.strategy = strategy
+		return nil
+	}
+}
+
+// WithLocalFailureDomain sets the failure domain of the client, e.g. {"REGION": "EU", "ZONE": "EU-1"},
+// so operations are routed to instances in the same failure domain and fail over to remote ones
+// only when none of the local instances is available. The balance strategy chooses among
+// the local or the remote instances, see [strategies.NewLocalityStrategy].
+func WithLocalFailureDomain(domain map[string]string) PoolOption {
+	return func(p *poolOpts) error {
+		if len(domain) == 0 {
+			return fmt.Errorf("local failure domain is empty")
+		}
+		for key, value := range domain {
+			if key == "" || value == "" {
+				return fmt.Errorf("local failure domain has empty key or value")
+			}
+		}
+		p.localFailureDomain = maps.Clone(domain)
+		return nil
+	}
+}
+
+// instanceStrategy returns the strategy configured for the pool or nil if the default one must be used.
+func (p *poolOpts) instanceStrategy() strategies.InstanceStrategy {
+	if p.localFailureDomain != nil {
+		return strategies.NewLocalityStrategy(p.localFailureDomain, p.strategy)
+	}
+
+	return p.strategy
+}
+
+// WithLogger sets a custom pool internal logger to print information.
+// The logger is used only by this pool, pools created without it use the process-wide default logger.
 func WithLogger(customLogger logger.Logger) PoolOption {
 	return func(p *poolOpts) error {
 		if customLogger == nil {
@@ -40,14 +98,37 @@ func WithLogger(customLogger logger.Logger) PoolOption {
 	}
 }
 
-// WithLogLevel set a logger level for pool internal logger
+// WithLogLevel set a logger level for pool internal logger.
+// Records are filtered by the pool, so neither the process-wide default logger
+// nor the one set with WithLogger is affected.
 func WithLogLevel(level logger.LogLevel) PoolOption {
 	return func(p *poolOpts) error {
-		p.logLevel = level
+		if _, err := level.String(); err != nil {
+			return err
+		}
+		p.logLevel = &level
 		return nil
 	}
 }
 
+// poolLogger returns the logger configured for the pool or nil if the default logger must be used.
+func (p *poolOpts) poolLogger() logger.Logger {
+	if p.logLevel == nil {
+		return p.logger
+	}
+
+	if p.logger == nil {
+		l := logger.NewDefaultLogger()
+		// NOTE: the level is validated by WithLogLevel
+		_ = l.SetLevel(*p.logLevel)
+		return l
+	}
+
+	// The custom logger may be shared with other pools, so it is wrapped instead of changing its level
+	l, _ := logger.NewLevelLogger(p.logger, *p.logLevel)
+	return l
+}
+
 // WithDisableTopologyManaging disables backfround poller that actualizes topology
 func WithDisableTopologyManaging() PoolOption {
 	return func(p *poolOpts) error {
@@ -75,3 +156,204 @@ func WithMaxConnPerInstance(maxConns int32) PoolOption {
 	}
 
 }
+
+// WithTiers restricts the pool to instances of tiers, e.g. WithTiers("router").
+// Instances of other tiers are neither routed nor connected to, except the initial one
+// that is used for discovery and removed afterwards. Use [WithTier] to pick one of the tiers per operation.
+func WithTiers(tiers ...string) PoolOption {
+	return func(p *poolOpts) error {
+		if len(tiers) == 0 {
+			return fmt.Errorf("tiers are empty")
+		}
+		if slices.Contains(tiers, "") {
+			return fmt.Errorf("tier name is empty")
+		}
+		p.tiers = slices.Clone(tiers)
+		return nil
+	}
+}
+
+// WithTierMaxConns sets the maximum number of connections to every instance of tier,
+// overriding WithMaxConnPerInstance. It doesn't affect the initial instance, which is connected to
+// before its tier is known. The option may be passed several times for different tiers.
+func WithTierMaxConns(tier string, maxConns int32) PoolOption {
+	return func(p *poolOpts) error {
+		if len(tier) == 0 {
+			return fmt.Errorf("tier name is empty")
+		}
+		if maxConns <= 0 {
+			return fmt.Errorf("max connections of tier %s must be positive", tier)
+		}
+		if p.tierMaxConns == nil {
+			p.tierMaxConns = make(map[string]int32)
+		}
+		p.tierMaxConns[tier] = maxConns
+		return nil
+	}
+}
+
+// WithDefaultRoutingMode sets the routing mode of all pool operations, unless it is set
+// per operation with [WithRoutingMode]. By default Exec, CopyFrom, SendBatch, Begin and Acquire
+// prefer replicaset leaders, while Query and QueryRow are routed to any instance.
+func WithDefaultRoutingMode(mode RoutingMode) PoolOption {
+	return func(p *poolOpts) error {
+		if err := mode.validate(); err != nil {
+			return err
+		}
+		p.routingMode = &mode
+		return nil
+	}
+}
+
+// WithBucketResolver sets the resolver of replicasets storing buckets,
+// so [Pool.QueryByKey] and [Pool.ExecByKey] are routed straight to them.
+// Without it they are routed only to the tier of the table.
+func WithBucketResolver(resolver BucketResolver) PoolOption {
+	return func(p *poolOpts) error {
+		if resolver == nil {
+			return fmt.Errorf("bucket resolver is nil")
+		}
+		p.bucketResolver = resolver
+		return nil
+	}
+}
+
+// WithInstanceWaitTimeout sets how long pool operations wait for an instance
+// to come back online when there are none available.
+// By default operations fail with ErrNoAvailableInstances immediately.
+func WithInstanceWaitTimeout(timeout time.Duration) PoolOption {
+	return func(p *poolOpts) error {
+		if timeout < 0 {
+			return fmt.Errorf("instance wait timeout is negative")
+		}
+		p.instanceWaitTimeout = timeout
+		return nil
+	}
+}
+
+// WithRetryPolicy enables retrying of operations failed with transient instance errors
+// on another instance chosen by the balance strategy. Operations are retried only if nothing was sent
+// to the failed instance, unless they are marked idempotent with [WithIdempotent].
+// See [WithoutRetry] to disable retries for a single operation.
+func WithRetryPolicy(policy RetryPolicy) PoolOption {
+	return func(p *poolOpts) error {
+		if policy == nil {
+			return fmt.Errorf("retry policy is nil")
+		}
+		p.retryPolicy = policy
+		return nil
+	}
+}
+
+// WithCircuitBreaker enables per-instance circuit breakers. After config.FailureThreshold
+// consecutive connection errors the instance is excluded from balancing and probed
+// every config.OpenTimeout until it responds again.
+func WithCircuitBreaker(config CircuitBreakerConfig) PoolOption {
+	return func(p *poolOpts) error {
+		if config.FailureThreshold <= 0 {
+			return fmt.Errorf("circuit breaker failure threshold must be positive")
+		}
+		if config.OpenTimeout <= 0 {
+			return fmt.Errorf("circuit breaker open timeout must be positive")
+		}
+		p.circuitBreaker = &config
+		return nil
+	}
+}
+
+// WithPollInterval sets how often the topology is polled by the background topology manager.
+// Default is 500ms.
+func WithPollInterval(interval time.Duration) PoolOption {
+	return func(p *poolOpts) error {
+		if interval <= 0 {
+			return fmt.Errorf("poll interval must be positive")
+		}
+		p.producerConfig.pollPeriod = interval
+		return nil
+	}
+}
+
+// WithTopologyQueryTimeout sets the timeout of a single topology poll. Default is 3s.
+func WithTopologyQueryTimeout(timeout time.Duration) PoolOption {
+	return func(p *poolOpts) error {
+		if timeout <= 0 {
+			return fmt.Errorf("topology query timeout must be positive")
+		}
+		p.producerConfig.queryTimeout = timeout
+		return nil
+	}
+}
+
+// WithMaxPollBackoff caps the delay between topology polls when they keep failing.
+// The delay grows exponentially with jitter starting from the poll interval. Default is 30s.
+// A value not greater than the poll interval disables backoff.
+func WithMaxPollBackoff(maxBackoff time.Duration) PoolOption {
+	return func(p *poolOpts) error {
+		if maxBackoff < 0 {
+			return fmt.Errorf("max poll backoff is negative")
+		}
+		p.producerConfig.maxBackoff = maxBackoff
+		return nil
+	}
+}
+
+// WithEventBufferSize sets the capacity of the channel topology changes are passed through
+// from the poller to the topology manager. Default is 10.
+func WithEventBufferSize(size int) PoolOption {
+	return func(p *poolOpts) error {
+		if size < 0 {
+			return fmt.Errorf("event buffer size is negative")
+		}
+		p.eventBufferSize = size
+		return nil
+	}
+}
+
+// WithDrainTimeout sets how long the pool of a removed instance waits for checked-out connections
+// to be released before it is closed. Connections released after the timeout are closed immediately.
+// Default is 30s.
+func WithDrainTimeout(timeout time.Duration) PoolOption {
+	return func(p *poolOpts) error {
+		if timeout < 0 {
+			return fmt.Errorf("drain timeout is negative")
+		}
+		p.drainTimeout = &timeout
+		return nil
+	}
+}
+
+// WithTopologyHooks sets functions called when instances are added to or removed from routing
+// and when their state changes. See [TopologyHooks] and [Pool.SubscribeTopology].
+func WithTopologyHooks(hooks TopologyHooks) PoolOption {
+	return func(p *poolOpts) error {
+		if hooks.OnInstanceAdded == nil && hooks.OnInstanceRemoved == nil && hooks.OnStateChanged == nil {
+			return fmt.Errorf("topology hooks are empty")
+		}
+		p.topologyHooks = hooks
+		return nil
+	}
+}
+
+// WithTracer enables OpenTelemetry tracing of pool operations with tracer.
+//
+// Query, QueryRow, Exec, CopyFrom, SendBatch, Begin and Acquire create a client span covering
+// instance selection and all retry attempts. Spans have db.system=picodata, picodata.balance_strategy,
+// and picodata.instance.address, picodata.retry.attempt and picodata.topology.generation of the last attempt.
+// Failed attempts that are retried are recorded as span events. Topology polls are traced as well.
+//
+// Spans of Query and QueryRow end when the rows are read or closed, spans of SendBatch when the batch results
+// are closed and spans of Begin when the transaction is committed or rolled back, recording their errors.
+// The span of Acquire covers acquiring the connection only.
+//
+// A pgx.QueryTracer set on the pool config is kept as is and copied to every instance,
+// so its spans become children of the pool spans.
+func WithTracer(tracer trace.Tracer) PoolOption {
+	return func(p *poolOpts) error {
+		if tracer == nil {
+			return fmt.Errorf("tracer is nil")
+		}
+		p.tracer = tracer
+		p.producerConfig.tracer = tracer
+		return nil
+	}
+}
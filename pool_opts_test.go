@@ -0,0 +1,120 @@
+package picodata
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/picodata/picodata-go/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// levelRecorder is a logger recording levels of the records it was asked to log
+type levelRecorder struct {
+	level  logger.LogLevel
+	levels []logger.LogLevel
+}
+
+func (r *levelRecorder) Log(level logger.LogLevel, _ string, _ ...any) {
+	r.levels = append(r.levels, level)
+}
+
+func (r *levelRecorder) SetLevel(level logger.LogLevel) error {
+	r.level = level
+	return nil
+}
+
+func TestPoolOptions(t *testing.T) {
+	t.Run("TestInvalidOptions", func(t *testing.T) {
+		invalid := map[string]PoolOption{
+			"BalanceStrategy":     WithBalanceStrategy(nil),
+			"InstanceStrategy":    WithInstanceStrategy(nil),
+			"Logger":              WithLogger(nil),
+			"ServiceConnString":   WithServiceConnString(""),
+			"InstanceWaitTimeout": WithInstanceWaitTimeout(-time.Second),
+			"RetryPolicy":         WithRetryPolicy(nil),
+			"CircuitBreaker":      WithCircuitBreaker(CircuitBreakerConfig{OpenTimeout: time.Second}),
+			"CircuitBreakerOpen":  WithCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 1}),
+			"PollInterval":        WithPollInterval(0),
+			"TopologyTimeout":     WithTopologyQueryTimeout(0),
+			"MaxPollBackoff":      WithMaxPollBackoff(-time.Second),
+			"EventBufferSize":     WithEventBufferSize(-1),
+			"DrainTimeout":        WithDrainTimeout(-time.Second),
+			"TopologyHooks":       WithTopologyHooks(TopologyHooks{}),
+			"LogLevel":            WithLogLevel(logger.LogLevel(100)),
+			"LocalFailureDomain":  WithLocalFailureDomain(nil),
+			"FailureDomainValue":  WithLocalFailureDomain(map[string]string{"ZONE": ""}),
+			"Tiers":               WithTiers(),
+			"TierName":            WithTiers("router", ""),
+			"TierMaxConns":        WithTierMaxConns("router", 0),
+			"TierMaxConnsName":    WithTierMaxConns("", 1),
+			"RoutingMode":         WithDefaultRoutingMode(RoutingMode(100)),
+			"BucketResolver":      WithBucketResolver(nil),
+		}
+
+		for name, opt := range invalid {
+			assert.Error(t, opt(&poolOpts{}), name)
+		}
+	})
+
+	t.Run("TestTopologyPolling", func(t *testing.T) {
+		opts := &poolOpts{producerConfig: defaultProducerConfig(), eventBufferSize: defaultEventBufferSize}
+
+		for _, opt := range []PoolOption{
+			WithPollInterval(time.Second),
+			WithTopologyQueryTimeout(5 * time.Second),
+			WithMaxPollBackoff(time.Minute),
+			WithEventBufferSize(100),
+		} {
+			assert.NoError(t, opt(opts))
+		}
+
+		assert.Equal(t, producerConfig{pollPeriod: time.Second, queryTimeout: 5 * time.Second, maxBackoff: time.Minute}, opts.producerConfig)
+		assert.Equal(t, 100, opts.eventBufferSize)
+	})
+
+	t.Run("TestPerPoolLogger", func(t *testing.T) {
+		// Pool without logger options uses the default one
+		assert.Nil(t, (&poolOpts{}).poolLogger())
+
+		debugOpts, errorOpts := &poolOpts{}, &poolOpts{}
+		require.NoError(t, WithLogLevel(logger.LevelDebug)(debugOpts))
+		require.NoError(t, WithLogLevel(logger.LevelError)(errorOpts))
+
+		debugLogger, errorLogger := debugOpts.poolLogger(), errorOpts.poolLogger()
+		require.NotNil(t, debugLogger)
+		require.NotNil(t, errorLogger)
+		assert.NotSame(t, debugLogger, errorLogger)
+
+		// The level of a shared custom logger is not changed by pools using it
+		shared := &levelRecorder{level: logger.LevelInfo}
+		sharedOpts := &poolOpts{}
+		require.NoError(t, WithLogger(shared)(sharedOpts))
+		require.NoError(t, WithLogLevel(logger.LevelError)(sharedOpts))
+		sharedOpts.poolLogger().Log(logger.LevelWarn, "")
+		sharedOpts.poolLogger().Log(logger.LevelError, "")
+		assert.Equal(t, logger.LevelInfo, shared.level)
+		assert.Equal(t, []logger.LogLevel{logger.LevelError}, shared.levels)
+
+		// Pool loggers are used concurrently with the process-wide one
+		var wg sync.WaitGroup
+		for _, l := range []logger.Logger{debugLogger, errorLogger} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 10 {
+					l.Log(logger.LevelNone, "")
+					_ = l.SetLevel(logger.LevelWarn)
+					logger.Default().Log(logger.LevelNone, "")
+				}
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.SetDefaultLogger(logger.NewDefaultLogger())
+		}()
+		wg.Wait()
+	})
+}
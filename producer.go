@@ -7,39 +7,84 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/picodata/picodata-go/logger"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	pollPeriod      = 500 * time.Millisecond
+	defaultPollPeriod           = 500 * time.Millisecond
+	defaultTopologyQueryTimeout = 3 * time.Second
+	defaultMaxPollBackoff       = 30 * time.Second
+	defaultEventBufferSize      = 10
+
 	connsStateQuery = `
 		SELECT ppa.address,
-		       pi.current_state
+		       pi.current_state,
+		       pi.target_state,
+		       pi.raft_id,
+		       pi.name,
+		       pi.replicaset_name,
+		       pi.tier,
+		       pi.failure_domain,
+		       pr.current_master_name
 		FROM   _pico_peer_address AS ppa
 		       JOIN _pico_instance AS pi
 		         ON ppa.raft_id = pi.raft_id
+		       LEFT JOIN _pico_replicaset AS pr
+		         ON pi.replicaset_name = pr.name
 		WHERE  connection_type = 'pgproto';
 	`
 )
 
 type connState struct {
 	address      string
-	currentState string
+	currentState InstanceState
+	targetState  InstanceState
+	meta         instanceMeta
+}
+
+func (s connState) equal(other connState) bool {
+	return s.address == other.address &&
+		s.currentState == other.currentState &&
+		s.targetState == other.targetState &&
+		s.meta.equal(other.meta)
+}
+
+// producerConfig defines how often and how long the topology is polled and how polls are traced.
+type producerConfig struct {
+	pollPeriod   time.Duration
+	queryTimeout time.Duration
+	// maxBackoff caps the delay between polls when they keep failing
+	maxBackoff time.Duration
+	// tracer is nil if tracing is disabled
+	tracer trace.Tracer
+}
+
+func defaultProducerConfig() producerConfig {
+	return producerConfig{
+		pollPeriod:   defaultPollPeriod,
+		queryTimeout: defaultTopologyQueryTimeout,
+		maxBackoff:   defaultMaxPollBackoff,
+	}
 }
 
 type stateProducer struct {
 	provider    *connectionProvider
 	serviceConn *pgxpool.Pool
 	filter      *stateFilter
+	config      producerConfig
+	logger      logger.Logger
+	// poll returns states of all instances reported by the cluster, it's getConnStates unless replaced in tests
+	poll func() ([]connState, error)
 }
 
-func newStateProducer(provider *connectionProvider, serviceConnString string) (*stateProducer, error) {
+func newStateProducer(provider *connectionProvider, serviceConnString string, config producerConfig) (*stateProducer, error) {
 	initConnConfig := provider.config()
 	initConnAddr := fmt.Sprintf("%s:%d", initConnConfig.ConnConfig.Host, initConnConfig.ConnConfig.Port)
 
 	var serviceConn *pgxpool.Pool
 	if len(serviceConnString) != 0 {
 
-		ctx, cf := context.WithTimeout(context.Background(), 3*time.Second)
+		ctx, cf := context.WithTimeout(context.Background(), config.queryTimeout)
 		defer cf()
 		sc, err := pgxpool.New(ctx, serviceConnString)
 		if err != nil {
@@ -48,38 +93,57 @@ func newStateProducer(provider *connectionProvider, serviceConnString string) (*
 		serviceConn = sc
 	}
 
-	return &stateProducer{
+	p := &stateProducer{
 		provider:    provider,
 		serviceConn: serviceConn,
-		filter:      newStateFilter(initConnAddr, stateOnline),
-	}, nil
+		filter:      newStateFilter(connState{address: initConnAddr, currentState: InstanceStateOnline, targetState: InstanceStateOnline}),
+		config:      config,
+		logger:      provider.logger,
+	}
+	p.poll = p.getConnStates
+
+	return p, nil
 }
 
 func (p *stateProducer) runProducing(eventChan chan<- event, stopChan chan struct{}) {
 	const op = "producer: runProducing"
 
-	ticker := time.NewTicker(pollPeriod)
+	timer := time.NewTimer(p.config.pollPeriod)
+	// failures is the number of consecutive failed polls
+	failures := 0
 
 	for {
 		select {
 		case <-stopChan:
 			close(eventChan)
-			ticker.Stop()
+			timer.Stop()
 			return
-		case <-ticker.C:
+		case <-timer.C:
 			// noop
 		}
 
-		connStates, err := p.getConnStates()
+		pollStart := time.Now()
+		connStates, err := p.poll()
+		p.provider.metrics.topologyPolled(time.Since(pollStart), err)
 		if err != nil {
-			logger.Log(logger.LevelError, "%s: %v", op, err)
+			// Back off, so a struggling cluster isn't polled by every client every pollPeriod
+			failures++
+			delay := backoff(p.config.pollPeriod, max(p.config.maxBackoff, p.config.pollPeriod), failures+1)
+			logger.LogFields(p.logger, logger.LevelError, "topology poll failed",
+				logger.String(logger.KeyOp, op), logger.Any("next_poll_in", delay), logger.Err(err))
+			timer.Reset(delay)
 			continue
 		}
+		failures = 0
+		timer.Reset(p.config.pollPeriod)
 
 		filteredConnStates := p.filter.filterNewOrUpdated(connStates)
+		// Addresses missing from a successful poll are removed, e.g. once the instance is expelled
+		// or its address changes, so they aren't routed to forever
+		filteredConnStates = append(filteredConnStates, p.filter.filterRemoved(connStates)...)
 
 		for _, state := range filteredConnStates {
-			eventChan <- event{address: state.address, state: state.currentState}
+			eventChan <- event{address: state.address, state: state.currentState, targetState: state.targetState, meta: state.meta}
 		}
 	}
 }
@@ -87,59 +151,52 @@ func (p *stateProducer) runProducing(eventChan chan<- event, stopChan chan struc
 func (p *stateProducer) getConnStates() ([]connState, error) {
 	const op = "producer: getConnStates"
 
-	connsState := make([]connState, 0, 1)
-
 	// TODO: Maybe we need a separate balancer for producer?
 	// In that case, we will also need to track pool length in two places.
-	ctx, cf := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cf := context.WithTimeout(context.Background(), p.config.queryTimeout)
 	defer cf()
+
+	ctx, span := tracerOrNoop(p.config.tracer).Start(ctx, spanTopologyPoll, trace.WithSpanKind(trace.SpanKindClient))
+	span.SetAttributes(dbSystemPicodata, attrTopologyGeneration.Int64(int64(p.provider.currentGeneration())))
+
 	var conn *pgxpool.Pool
 	if p.serviceConn != nil {
 		conn = p.serviceConn
 	} else {
-		conn = p.provider.nextConnection()
-	}
-
-	rows, err := conn.Query(ctx, connsStateQuery)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var connAddr string
-		var connFetchedState []any // contains [string, int]
-
-		if err := rows.Scan(&connAddr, &connFetchedState); err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
-		}
-
-		connStateStr, ok := connFetchedState[0].(string)
-		if !ok {
-			return nil, fmt.Errorf("%s: %s state must be a string, but has type %T", op, connAddr, connFetchedState[0])
+		inst, err := p.provider.nextConnection(ctx)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", op, err)
+			endSpan(span, err)
+			return nil, err
 		}
-
-		connsState = append(connsState, connState{address: connAddr, currentState: connStateStr})
+		conn = inst.pool
 	}
+	span.SetAttributes(attrInstanceAddress.String(poolAddress(conn)))
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+	states, err := getTopology(ctx, conn)
+	if err == nil {
+		span.SetAttributes(attrTopologyInstances.Int(len(states)))
 	}
+	endSpan(span, err)
 
-	return connsState, nil
+	return states, err
 }
 
 // stateFilter keeps track of known states and filters new/updated ones
 type stateFilter struct {
-	knownConns map[string]string // map[address]state
+	knownConns map[string]connState // map[address]state
+	// polled contains addresses reported by the last successful poll.
+	// The initial address is not included until the cluster reports it.
+	polled map[string]struct{}
 }
 
-func newStateFilter(initConnAddr, initConnState string) *stateFilter {
-	knownConns := make(map[string]string)
-	knownConns[initConnAddr] = initConnState
+func newStateFilter(initConn connState) *stateFilter {
+	knownConns := make(map[string]connState)
+	knownConns[initConn.address] = initConn
 
 	return &stateFilter{
 		knownConns: knownConns,
+		polled:     make(map[string]struct{}),
 	}
 }
 
@@ -148,12 +205,36 @@ func (sf *stateFilter) filterNewOrUpdated(newConnStates []connState) []connState
 	result := make([]connState, 0, len(newConnStates))
 
 	for _, s := range newConnStates {
-		if currentState, exists := sf.knownConns[s.address]; !exists || currentState != s.currentState {
+		if known, exists := sf.knownConns[s.address]; !exists || !known.equal(s) {
 			// This is either a new address or the state has changed
 			result = append(result, s)
-			sf.knownConns[s.address] = s.currentState
+			sf.knownConns[s.address] = s
+		}
+	}
+
+	return result
+}
+
+// filterRemoved returns Offline states of addresses reported by the previous poll, but missing from newConnStates,
+// and forgets them.
+func (sf *stateFilter) filterRemoved(newConnStates []connState) []connState {
+	polled := make(map[string]struct{}, len(newConnStates))
+	for _, s := range newConnStates {
+		polled[s.address] = struct{}{}
+	}
+
+	var result []connState
+	for address := range sf.polled {
+		if _, ok := polled[address]; ok {
+			continue
 		}
+
+		removed := sf.knownConns[address]
+		removed.currentState, removed.targetState = InstanceStateOffline, InstanceStateOffline
+		result = append(result, removed)
+		delete(sf.knownConns, address)
 	}
+	sf.polled = polled
 
 	return result
 }
@@ -2,6 +2,7 @@ package picodata
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -103,7 +104,7 @@ func runProducerTestContainers(t *testing.T) {
 	stopChan := make(chan struct{})
 
 	prov := newConnectionProvider(pool, 1)
-	producer, err := newStateProducer(prov, createPsql(adminPassword, "0.0.0.0:55432"))
+	producer, err := newStateProducer(prov, createPsql(adminPassword, "0.0.0.0:55432"), defaultProducerConfig())
 	assert.NoError(t, err)
 	go producer.runProducing(eventChan, stopChan)
 
@@ -149,7 +150,7 @@ func runProducerTestCI(t *testing.T) {
 	stopChan := make(chan struct{})
 
 	prov := newConnectionProvider(pool, 1)
-	producer, err := newStateProducer(prov, createPsql(os.Getenv("PICODATA_ADMIN_PASSWORD"), "picodata-1:5432"))
+	producer, err := newStateProducer(prov, createPsql(os.Getenv("PICODATA_ADMIN_PASSWORD"), "picodata-1:5432"), defaultProducerConfig())
 	assert.NoError(t, err)
 	go producer.runProducing(eventChan, stopChan)
 
@@ -177,3 +178,90 @@ func runProducerTestCI(t *testing.T) {
 		assert.NotZero(t, occurence)
 	}
 }
+
+func TestStopWhileBackingOff(t *testing.T) {
+	// Provider without instances makes every poll fail immediately
+	prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+	prov.removeConn("127.0.0.1:5432")
+
+	config := producerConfig{
+		pollPeriod:   5 * time.Millisecond,
+		queryTimeout: time.Second,
+		maxBackoff:   time.Hour,
+	}
+	producer, err := newStateProducer(prov, "", config)
+	require.NoError(t, err)
+
+	eventChan := make(chan event)
+	stopChan := make(chan struct{})
+	go producer.runProducing(eventChan, stopChan)
+
+	// Let the delay between polls grow, the producer must stop without waiting for the next poll
+	time.Sleep(200 * time.Millisecond)
+	close(stopChan)
+
+	stopped := make(chan struct{})
+	go func() {
+		// Channel is closed once producer is stopped
+		for range eventChan {
+			t.Error("no events expected without instances")
+		}
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("producer is not stopped")
+	}
+}
+
+func TestMissingInstancesRemoved(t *testing.T) {
+	prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+	defer prov.close()
+
+	config := producerConfig{pollPeriod: 5 * time.Millisecond, queryTimeout: time.Second, maxBackoff: time.Second}
+	producer, err := newStateProducer(prov, "", config)
+	require.NoError(t, err)
+
+	online := func(address string, raftID uint64) connState {
+		return connState{address: address, currentState: InstanceStateOnline, targetState: InstanceStateOnline, meta: instanceMeta{raftID: raftID}}
+	}
+	polls := [][]connState{
+		{online("127.0.0.1:5432", 1), online("127.0.0.1:5433", 2), online("127.0.0.1:5434", 3)},
+		// The row of the expelled instance 3 is deleted
+		{online("127.0.0.1:5432", 1), online("127.0.0.1:5433", 2)},
+		// Instance 2 changes its address
+		{online("127.0.0.1:5432", 1), online("127.0.0.1:5435", 2)},
+	}
+	polled := make(chan struct{})
+	producer.poll = func() ([]connState, error) {
+		if len(polls) == 0 {
+			close(polled)
+			return nil, errors.New("no more polls")
+		}
+		states := polls[0]
+		polls = polls[1:]
+		return states, nil
+	}
+
+	eventChan := make(chan event, 10)
+	stopChan := make(chan struct{})
+	go producer.runProducing(eventChan, stopChan)
+
+	manager := newTopologyManager(prov, nil)
+	processed := make(chan struct{})
+	go func() {
+		manager.runProcessing(eventChan)
+		close(processed)
+	}()
+
+	<-polled
+	close(stopChan)
+	<-processed
+
+	conns := prov.connsMap()
+	assert.Len(t, conns, 2)
+	assert.Contains(t, conns, "127.0.0.1:5432")
+	assert.Contains(t, conns, "127.0.0.1:5435")
+}
@@ -2,10 +2,14 @@ package picodata
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/picodata/picodata-go/logger"
 	"github.com/picodata/picodata-go/strategies"
@@ -13,84 +17,563 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// instance is a Picodata instance the provider routes operations to.
+type instance struct {
+	address string
+	pool    *pgxpool.Pool
+	// breaker is nil if circuit breaking is disabled
+	breaker *circuitBreaker
+	// meta is updated by the topology manager, guarded by connectionProvider mu
+	meta instanceMeta
+}
+
+// instanceStatus is the last known state of an instance reported by the cluster.
+type instanceStatus struct {
+	currentState InstanceState
+	targetState  InstanceState
+	meta         instanceMeta
+	changedAt    time.Time
+}
+
+// available reports whether the instance may receive traffic.
+func (i *instance) available() bool {
+	return i.breaker == nil || i.breaker.allow()
+}
+
+// reportResult feeds the circuit breaker with the result of an operation executed on the instance.
+func (i *instance) reportResult(err error) {
+	if i.breaker == nil {
+		return
+	}
+
+	switch {
+	case err == nil:
+		i.breaker.onSuccess()
+	case IsRetriable(err):
+		i.breaker.onFailure()
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// Says nothing about the instance
+	default:
+		// The instance has responded with an error, so it is reachable
+		i.breaker.onSuccess()
+	}
+}
+
+var _ strategies.Instance = (*instance)(nil)
+
+func (i *instance) Address() string {
+	return i.address
+}
+
+// Metadata must be called with connectionProvider mu held, which is the case during balancing.
+func (i *instance) Metadata() strategies.Metadata {
+	return strategies.Metadata{
+		RaftID:         i.meta.raftID,
+		Name:           i.meta.name,
+		ReplicasetName: i.meta.replicasetName,
+		Tier:           i.meta.tier,
+		FailureDomain:  i.meta.failureDomain,
+		Leader:         i.meta.leader,
+	}
+}
+
+func (i *instance) Stat() *pgxpool.Stat {
+	return i.pool.Stat()
+}
+
+func (i *instance) AcquiredConns() int32 {
+	return i.pool.Stat().AcquiredConns()
+}
+
+const (
+	defaultDrainTimeout = 30 * time.Second
+	// drainCheckPeriod is how often a draining pool is checked for checked-out connections
+	drainCheckPeriod = 50 * time.Millisecond
+)
+
 type connectionProvider struct {
 	mu                sync.RWMutex
-	current           uint64
 	connectionsConfig *pgxpool.Config
-	connections       []*pgxpool.Pool
+	connections       []*instance
 	// Key: instance address
-	// Value: index of corresponding *pgxpool.Pool in [connectionProvider] connections slice
+	// Value: index of corresponding *instance in [connectionProvider] connections slice
 	connectionsMap        map[string]int
-	balanceStrategy       strategies.BalanceStrategy
+	strategy              strategies.InstanceStrategy
 	connectionPerInstance int32
+	// instanceAvailable is closed and replaced every time an instance becomes available,
+	// so goroutines waiting for an available instance can be woken up.
+	instanceAvailable   chan struct{}
+	instanceWaitTimeout time.Duration
+	// breakerConfig is nil if circuit breaking is disabled
+	breakerConfig *CircuitBreakerConfig
+	// draining contains removed instances whose pools are closed once checked-out connections are released
+	draining     map[*instance]struct{}
+	drainTimeout time.Duration
+	// removedStat accumulates cumulative counters of closed pools of removed instances
+	removedStat Stat
+	// Key: instance address
+	// Value: last known status of every instance reported by the cluster, routed or not
+	statuses map[string]*instanceStatus
+	// generation is incremented every time an instance is added or removed
+	generation uint64
+	// metrics are shared with other pool components
+	metrics *poolMetrics
+	// logger is shared with other pool components
+	logger logger.Logger
+	// tiers are the only tiers whose instances are routed, nil means all tiers
+	tiers map[string]struct{}
+	// tierMaxConns overrides connectionPerInstance for instances of the tier
+	tierMaxConns map[string]int32
 }
 
 func newConnectionProvider(initConn *pgxpool.Pool, connPerInstance int32) *connectionProvider {
-	connPool := make([]*pgxpool.Pool, 0, 1)
+	connPool := make([]*instance, 0, 1)
 	connMap := make(map[string]int, 1)
 
-	connPool = append(connPool, initConn)
-	addr := fmt.Sprintf("%s:%d", initConn.Config().ConnConfig.Host, initConn.Config().ConnConfig.Port)
-	connMap[addr] = 0
+	initAddr := poolAddress(initConn)
+	connPool = append(connPool, &instance{address: initAddr, pool: initConn})
+	connMap[initAddr] = 0
 
 	return &connectionProvider{
-		current:               0,
 		connectionsConfig:     initConn.Config().Copy(),
 		connections:           connPool,
 		connectionsMap:        connMap,
-		balanceStrategy:       strategies.NewRoundRobinStrategy(),
+		strategy:              strategies.FromBalanceStrategy(strategies.NewRoundRobinStrategy()),
 		connectionPerInstance: connPerInstance,
+		instanceAvailable:     make(chan struct{}),
+		draining:              make(map[*instance]struct{}),
+		drainTimeout:          defaultDrainTimeout,
+		statuses:              make(map[string]*instanceStatus),
+		metrics:               newPoolMetrics(),
+		logger:                logger.Default(),
 	}
 }
 
 func (p *connectionProvider) setBalanceStrategy(s strategies.BalanceStrategy) {
+	p.setStrategy(strategies.FromBalanceStrategy(s))
+}
+
+func (p *connectionProvider) setStrategy(s strategies.InstanceStrategy) {
 	// NOTE: we use this function only in init stage, but
 	// -race flag will complain about using provider in different goroutines
 	// so the mutex is essential here.
 	p.mu.Lock()
-	p.balanceStrategy = s
+	p.strategy = s
+	p.mu.Unlock()
+}
+
+func (p *connectionProvider) setInstanceWaitTimeout(timeout time.Duration) {
+	p.mu.Lock()
+	p.instanceWaitTimeout = timeout
+	p.mu.Unlock()
+}
+
+func (p *connectionProvider) setDrainTimeout(timeout time.Duration) {
+	p.mu.Lock()
+	p.drainTimeout = timeout
+	p.mu.Unlock()
+}
+
+// setCircuitBreaker enables circuit breaking for all current and future instances.
+func (p *connectionProvider) setCircuitBreaker(config CircuitBreakerConfig) {
+	p.mu.Lock()
+	p.breakerConfig = &config
+	for _, inst := range p.connections {
+		if inst.breaker == nil {
+			inst.breaker = p.newBreaker(inst)
+		}
+	}
+	p.mu.Unlock()
+}
+
+func (p *connectionProvider) newBreaker(inst *instance) *circuitBreaker {
+	probe := func(ctx context.Context) error {
+		return pingPool(ctx, inst.pool)
+	}
+	return newCircuitBreaker(inst.address, *p.breakerConfig, probe, p.notifyAvailable, p.logger)
+}
+
+// setTiers restricts routing to instances of tiers.
+func (p *connectionProvider) setTiers(tiers []string) {
+	p.mu.Lock()
+	p.tiers = make(map[string]struct{}, len(tiers))
+	for _, tier := range tiers {
+		p.tiers[tier] = struct{}{}
+	}
+	p.mu.Unlock()
+}
+
+func (p *connectionProvider) setTierMaxConns(tierMaxConns map[string]int32) {
+	p.mu.Lock()
+	p.tierMaxConns = tierMaxConns
+	p.mu.Unlock()
+}
+
+// tierAllowed reports whether instances of tier may be routed.
+func (p *connectionProvider) tierAllowed(tier string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.tiers == nil {
+		return true
+	}
+	_, ok := p.tiers[tier]
+	return ok
+}
+
+func (p *connectionProvider) setLogger(l logger.Logger) {
+	p.mu.Lock()
+	p.logger = l
 	p.mu.Unlock()
 }
 
+// notifyAvailable wakes up goroutines waiting for an available instance.
+func (p *connectionProvider) notifyAvailable() {
+	p.mu.Lock()
+	p.notifyAvailableLocked()
+	p.mu.Unlock()
+}
+
+// notifyAvailableLocked is the same as notifyAvailable, but must be called with mu held.
+func (p *connectionProvider) notifyAvailableLocked() {
+	close(p.instanceAvailable)
+	p.instanceAvailable = make(chan struct{})
+}
+
 func (p *connectionProvider) config() *pgxpool.Config {
 	return p.connectionsConfig.Copy()
 }
 
 func (p *connectionProvider) conns() []*pgxpool.Pool {
-	return p.connections
+	p.mu.RLock()
+	pools := make([]*pgxpool.Pool, 0, len(p.connections))
+	for _, inst := range p.connections {
+		pools = append(pools, inst.pool)
+	}
+	p.mu.RUnlock()
+
+	return pools
+}
+
+// stats returns statistics of routed instance pools keyed by address.
+func (p *connectionProvider) stats() map[string]*pgxpool.Stat {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stats := make(map[string]*pgxpool.Stat, len(p.connections))
+	for _, inst := range p.connections {
+		stats[inst.address] = inst.pool.Stat()
+	}
+
+	return stats
+}
+
+// stat returns statistics aggregated across routed instances.
+// Cumulative counters of removed instances, draining or closed, are included.
+func (p *connectionProvider) stat() *Stat {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stat := p.removedStat
+	for _, inst := range p.connections {
+		stat.add(inst.pool.Stat())
+	}
+	for inst := range p.draining {
+		stat.addCumulative(inst.pool.Stat())
+	}
+
+	return &stat
+}
+
+// close stops background activity of all instances and closes their pools, including draining ones.
+func (p *connectionProvider) close() {
+	p.mu.Lock()
+	instances := append([]*instance(nil), p.connections...)
+	for _, inst := range instances {
+		if inst.breaker != nil {
+			inst.breaker.stop()
+		}
+	}
+	for inst := range p.draining {
+		instances = append(instances, inst)
+	}
+	p.mu.Unlock()
+
+	for _, inst := range instances {
+		inst.pool.Close()
+	}
+}
+
+// updateState records the state and metadata of an instance reported by the cluster.
+// It reports whether the state of the instance has changed.
+func (p *connectionProvider) updateState(state connState) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	changed := false
+	status, ok := p.statuses[state.address]
+	if !ok {
+		status = &instanceStatus{}
+		p.statuses[state.address] = status
+	}
+	if !ok || status.currentState != state.currentState || status.targetState != state.targetState {
+		status.changedAt = time.Now()
+		changed = true
+	}
+	status.currentState = state.currentState
+	status.targetState = state.targetState
+	status.meta = state.meta
+
+	if index, ok := p.connectionsMap[state.address]; ok {
+		p.connections[index].meta = state.meta
+	}
+
+	return changed
+}
+
+// observe reports the latency of an operation attempt to the balance strategy if it takes latency into account.
+func (p *connectionProvider) observe(inst *instance, latency time.Duration, err error) {
+	p.mu.RLock()
+	observer, ok := p.strategy.(strategies.LatencyObserver)
+	p.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	switch {
+	case err == nil:
+		observer.Observe(inst.address, latency, nil)
+	case IsRetriable(err):
+		observer.Observe(inst.address, latency, err)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// Says nothing about the instance
+	default:
+		// The instance has responded with an error, so the latency is still meaningful
+		observer.Observe(inst.address, latency, nil)
+	}
+}
+
+// strategyType returns the type of the current balance strategy.
+func (p *connectionProvider) strategyType() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.strategy.Type()
+}
+
+// currentGeneration returns the number of times an instance was added or removed.
+func (p *connectionProvider) currentGeneration() uint64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.generation
+}
+
+// routed reports whether operations are routed to the instance with address.
+func (p *connectionProvider) routed(address string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	_, ok := p.connectionsMap[address]
+	return ok
+}
+
+// topology returns a snapshot of all known instances sorted by address.
+func (p *connectionProvider) topology() Topology {
+	p.mu.RLock()
+
+	topology := Topology{
+		Generation: p.generation,
+		Instances:  make([]InstanceInfo, 0, len(p.statuses)),
+	}
+
+	for address, status := range p.statuses {
+		info := InstanceInfo{
+			Address:        address,
+			RaftID:         status.meta.raftID,
+			Name:           status.meta.name,
+			ReplicasetName: status.meta.replicasetName,
+			Tier:           status.meta.tier,
+			FailureDomain:  maps.Clone(status.meta.failureDomain),
+			Leader:         status.meta.leader,
+			CurrentState:   status.currentState,
+			TargetState:    status.targetState,
+			StateChangedAt: status.changedAt,
+		}
+		if index, ok := p.connectionsMap[address]; ok {
+			fillRouting(&info, p.connections[index])
+		}
+		topology.Instances = append(topology.Instances, info)
+	}
+
+	// Instances routed without being reported by the cluster, e.g. the initial one before discovery
+	for _, inst := range p.connections {
+		if _, ok := p.statuses[inst.address]; ok {
+			continue
+		}
+		info := InstanceInfo{Address: inst.address}
+		fillRouting(&info, inst)
+		topology.Instances = append(topology.Instances, info)
+	}
+
+	p.mu.RUnlock()
+
+	slices.SortFunc(topology.Instances, func(a, b InstanceInfo) int {
+		return strings.Compare(a.Address, b.Address)
+	})
+
+	return topology
+}
+
+// fillRouting fills the parts of info that are known only for routed instances.
+func fillRouting(info *InstanceInfo, inst *instance) {
+	info.Routed = true
+	info.Stat = inst.pool.Stat()
+	if inst.breaker != nil {
+		info.CircuitState = inst.breaker.currentState()
+	}
+}
+
+func (p *connectionProvider) drainingCount() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return len(p.draining)
+}
+
+func (p *connectionProvider) size() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return len(p.connections)
 }
 
 func (p *connectionProvider) connsMap() map[string]*pgxpool.Pool {
 	p.mu.RLock()
 	connectionsMap := make(map[string]*pgxpool.Pool, len(p.connectionsMap))
 	for address, index := range p.connectionsMap {
-		connectionsMap[address] = p.connections[index]
+		connectionsMap[address] = p.connections[index].pool
 	}
 	p.mu.RUnlock()
 
 	return connectionsMap
 }
 
-func (p *connectionProvider) nextConnection() *pgxpool.Pool {
+// nextConnection returns the instance chosen by the balance strategy.
+// If there are no available instances, it waits up to instanceWaitTimeout for one to become available
+// and returns ErrNoAvailableInstances if none did.
+func (p *connectionProvider) nextConnection(ctx context.Context) (*instance, error) {
 	const op = "provider: nextConnection"
 
+	conn, instanceAvailable, waitTimeout := p.pick(ctx)
+	if conn != nil {
+		return conn, nil
+	}
+
+	if waitTimeout <= 0 {
+		logger.LogFields(p.logger, logger.LevelWarn, "no available instances", logger.String(logger.KeyOp, op))
+		return nil, ErrNoAvailableInstances
+	}
+
+	timer := time.NewTimer(waitTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-instanceAvailable:
+		case <-timer.C:
+			logger.LogFields(p.logger, logger.LevelWarn, "no instance became available",
+				logger.String(logger.KeyOp, op), logger.Any("wait_timeout", waitTimeout))
+			return nil, ErrNoAvailableInstances
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", ErrNoAvailableInstances, ctx.Err())
+		}
+
+		if conn, instanceAvailable, _ = p.pick(ctx); conn != nil {
+			return conn, nil
+		}
+	}
+}
+
+// nextConnectionExcept is the same as nextConnection, but avoids the excluded instance
+// as long as the balance strategy has other instances to choose from.
+func (p *connectionProvider) nextConnectionExcept(ctx context.Context, excluded *instance) (*instance, error) {
+	conn, err := p.nextConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 1; conn == excluded && i < p.size(); i++ {
+		if conn, err = p.nextConnection(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// pick returns the instance chosen by the balance strategy or nil if there are no available instances.
+// If ctx carries a tier, only instances of the tier are available. Instances of the replicaset
+// carried by ctx are preferred. Then instances are chosen according to the routing mode carried by ctx.
+// In the latter case the channel closed when an instance becomes available is returned as well.
+func (p *connectionProvider) pick(ctx context.Context) (*instance, <-chan struct{}, time.Duration) {
 	// NOTE: Ran benchmark with defered and sequential mutex
 	// ---------------------------------------
 	// NextConn        358411162   3.205 ns/op
 	// NextConnDefer   318601726   3.783 ns/op
 	p.mu.RLock()
 
-	if len(p.connections) == 0 {
-		logger.Log(logger.LevelWarn, "%s: connections slice is empty", op)
-		return nil
+	candidates := availableInstances(p.connections)
+	if tier, ok := tierFromContext(ctx); ok {
+		candidates = instancesOfTier(candidates, tier)
+	}
+	if tier, ok := preferredTierFromContext(ctx); ok {
+		candidates = instancesPreferringTier(candidates, tier)
+	}
+	if replicaset, ok := replicasetFromContext(ctx); ok {
+		candidates = instancesOfReplicaset(candidates, replicaset)
+	}
+	if mode, ok := routingModeFromContext(ctx); ok {
+		candidates = instancesForMode(candidates, mode)
+	}
+	if len(candidates) == 0 {
+		instanceAvailable, waitTimeout := p.instanceAvailable, p.instanceWaitTimeout
+		p.mu.RUnlock()
+		return nil, instanceAvailable, waitTimeout
 	}
 
-	index := p.balanceStrategy.Next(&p.current, uint64(len(p.connections)))
+	views := make([]strategies.Instance, len(candidates))
+	for i, inst := range candidates {
+		views[i] = inst
+	}
+	conn := candidates[p.strategy.Select(ctx, views)]
 
 	p.mu.RUnlock()
 
-	return p.connections[index]
+	return conn, nil, 0
+}
+
+// availableInstances returns instances that may receive traffic.
+// The slice is copied only if some of the instances are excluded.
+func availableInstances(instances []*instance) []*instance {
+	for i, inst := range instances {
+		if inst.available() {
+			continue
+		}
+
+		candidates := make([]*instance, i, len(instances))
+		copy(candidates, instances[:i])
+		for _, inst := range instances[i+1:] {
+			if inst.available() {
+				candidates = append(candidates, inst)
+			}
+		}
+		return candidates
+	}
+
+	return instances
 }
+
 func (p *connectionProvider) addConn(address string) error {
 	const op = "provider: addConn"
 
@@ -101,13 +584,27 @@ func (p *connectionProvider) addConn(address string) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	// Connection with address already exists
+	if _, ok := p.connectionsMap[address]; ok {
+		return nil
+	}
+
 	// Create a new config for connection
 	connCfg := p.connectionsConfig.Copy()
 	hostAndPort := strings.Split(address, ":")
+	if len(hostAndPort) != 2 {
+		return fmt.Errorf("%s: invalid address %q", op, address)
+	}
 	connCfg.ConnConfig.Host = hostAndPort[0]
 	if p.connectionPerInstance != 0 {
 		connCfg.MaxConns = int32(p.connectionPerInstance)
 	}
+	status, known := p.statuses[address]
+	if known {
+		if maxConns, ok := p.tierMaxConns[status.meta.tier]; ok {
+			connCfg.MaxConns = maxConns
+		}
+	}
 
 	// Convert port from string to integer
 	port, err := strconv.Atoi(hostAndPort[1])
@@ -123,11 +620,22 @@ func (p *connectionProvider) addConn(address string) error {
 		return err
 	}
 
+	inst := &instance{address: address, pool: conn}
+	if known {
+		inst.meta = status.meta
+	}
+	if p.breakerConfig != nil {
+		inst.breaker = p.newBreaker(inst)
+	}
+
 	// Add connection to the connection pool
-	p.connections = append(p.connections, conn)
+	p.connections = append(p.connections, inst)
 	p.connectionsMap[address] = len(p.connections) - 1
+	p.generation++
 
-	logger.Log(logger.LevelDebug, "%s: %s", op, address)
+	p.notifyAvailableLocked()
+
+	logger.LogFields(p.logger, logger.LevelDebug, "instance added", logger.String(logger.KeyOp, op), logger.String(logger.KeyAddress, address))
 
 	return nil
 }
@@ -138,10 +646,21 @@ func (p *connectionProvider) removeConn(address string) {
 	p.mu.Lock()
 
 	// If connection with address doesn't exist -> return
-	index := p.connectionsMap[address]
+	index, ok := p.connectionsMap[address]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
 
 	// Get address of last connection in pool
-	lastConnAddr := fmt.Sprintf("%s:%d", p.connections[len(p.connections)-1].Config().ConnConfig.Host, p.connections[len(p.connections)-1].Config().ConnConfig.Port)
+	lastConnAddr := p.connections[len(p.connections)-1].address
+
+	removed := p.connections[index]
+	if removed.breaker != nil {
+		removed.breaker.stop()
+	}
+	p.draining[removed] = struct{}{}
+	drainTimeout := p.drainTimeout
 
 	// Remove entry about connection from connMap
 	delete(p.connectionsMap, address)
@@ -155,8 +674,53 @@ func (p *connectionProvider) removeConn(address string) {
 	}
 	// Delete connection from connSlice by truncating it
 	p.connections = p.connections[:len(p.connections)-1]
+	p.generation++
+
+	p.mu.Unlock()
+
+	go p.drain(removed, drainTimeout)
+
+	logger.LogFields(p.logger, logger.LevelDebug, "instance removed", logger.String(logger.KeyOp, op), logger.String(logger.KeyAddress, address))
+}
 
+// drain closes the pool of the removed instance once all checked-out connections are released
+// or drainTimeout is exceeded. In the latter case connections are closed as soon as they are released.
+func (p *connectionProvider) drain(inst *instance, drainTimeout time.Duration) {
+	const op = "provider: drain"
+
+	deadline := time.Now().Add(drainTimeout)
+	ticker := time.NewTicker(drainCheckPeriod)
+	defer ticker.Stop()
+
+	for {
+		acquired := inst.pool.Stat().AcquiredConns()
+		if acquired == 0 {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			logger.LogFields(p.logger, logger.LevelWarn, "drain timeout exceeded, closing pool with connections in use",
+				logger.String(logger.KeyOp, op), logger.String(logger.KeyAddress, inst.address), logger.Any("acquired", acquired))
+			break
+		}
+		<-ticker.C
+	}
+
+	// NOTE: pgxpool.Pool.Close is safe to call multiple times,
+	// so it doesn't matter if provider has been closed meanwhile.
+	inst.pool.Close()
+
+	p.mu.Lock()
+	// Counters are folded in the same critical section the instance stops draining in,
+	// so stat never counts it twice or misses it.
+	p.removedStat.addCumulative(inst.pool.Stat())
+	delete(p.draining, inst)
 	p.mu.Unlock()
 
-	logger.Log(logger.LevelDebug, "%s: %s", op, address)
+	logger.LogFields(p.logger, logger.LevelDebug, "pool is closed", logger.String(logger.KeyOp, op), logger.String(logger.KeyAddress, inst.address))
+}
+
+// poolAddress returns the instance address ("host:port") the pool connects to.
+func poolAddress(pool *pgxpool.Pool) string {
+	connConfig := pool.Config().ConnConfig
+	return fmt.Sprintf("%s:%d", connConfig.Host, connConfig.Port)
 }
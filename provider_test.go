@@ -4,9 +4,13 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/picodata/picodata-go/strategies"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -45,12 +49,14 @@ func TestProvider(t *testing.T) {
 		pool2 := newMockPool("127.0.0.1", 5433)
 
 		prov := newConnectionProvider(pool1, 1)
-		prov.connections = append(prov.connections, pool2)
+		prov.connections = append(prov.connections, &instance{address: poolAddress(pool2), pool: pool2})
 
 		prov.setBalanceStrategy(mockBalancerStrategy{})
 		// Always return second connection
 		for range 5 {
-			assert.Equal(t, pool2, prov.nextConnection())
+			conn, err := prov.nextConnection(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, pool2, conn.pool)
 		}
 	})
 
@@ -60,12 +66,14 @@ func TestProvider(t *testing.T) {
 
 		prov := newConnectionProvider(pool1, 1)
 		// Because we can
-		prov.connections = append(prov.connections, pool2)
+		prov.connections = append(prov.connections, &instance{address: poolAddress(pool2), pool: pool2})
 
 		// Default strategy is RoundRobin
-		assert.Equal(t, pool1, prov.nextConnection())
-		assert.Equal(t, pool2, prov.nextConnection())
-		assert.Equal(t, pool1, prov.nextConnection())
+		for _, want := range []*pgxpool.Pool{pool1, pool2, pool1} {
+			conn, err := prov.nextConnection(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, want, conn.pool)
+		}
 	})
 
 	t.Run("TestNextConnectionConcurrent", func(t *testing.T) {
@@ -80,9 +88,9 @@ func TestProvider(t *testing.T) {
 		pool3 := newMockPool(addr, ports[2])
 
 		prov := newConnectionProvider(pool1, 1)
-		prov.connections = append(prov.connections, pool2)
+		prov.connections = append(prov.connections, &instance{address: poolAddress(pool2), pool: pool2})
 		prov.connectionsMap[host2] = 1
-		prov.connections = append(prov.connections, pool3)
+		prov.connections = append(prov.connections, &instance{address: poolAddress(pool3), pool: pool3})
 		prov.connectionsMap[host3] = 2
 
 		goroutines := 200
@@ -93,7 +101,8 @@ func TestProvider(t *testing.T) {
 		for range goroutines {
 			go func() {
 				defer wg.Done()
-				conn := prov.nextConnection()
+				conn, err := prov.nextConnection(context.Background())
+				assert.NoError(t, err)
 				assert.NotNil(t, conn)
 			}()
 		}
@@ -160,9 +169,9 @@ func TestProvider(t *testing.T) {
 		pool2 := newMockPool(host, ports[1])
 		pool3 := newMockPool(host, ports[2])
 		prov := newConnectionProvider(pool1, 1)
-		prov.connections = append(prov.connections, pool2)
+		prov.connections = append(prov.connections, &instance{address: poolAddress(pool2), pool: pool2})
 		prov.connectionsMap[fmt.Sprintf("%s:%d", host, ports[1])] = 1
-		prov.connections = append(prov.connections, pool3)
+		prov.connections = append(prov.connections, &instance{address: poolAddress(pool3), pool: pool3})
 		prov.connectionsMap[fmt.Sprintf("%s:%d", host, ports[2])] = 2
 
 		prov.removeConn(fmt.Sprintf("%s:%d", host, ports[2]))
@@ -183,9 +192,9 @@ func TestProvider(t *testing.T) {
 		pool2 := newMockPool(host, ports[1])
 		pool3 := newMockPool(host, ports[2])
 		prov := newConnectionProvider(pool1, 1)
-		prov.connections = append(prov.connections, pool2)
+		prov.connections = append(prov.connections, &instance{address: poolAddress(pool2), pool: pool2})
 		prov.connectionsMap[fmt.Sprintf("%s:%d", host, ports[1])] = 1
-		prov.connections = append(prov.connections, pool3)
+		prov.connections = append(prov.connections, &instance{address: poolAddress(pool3), pool: pool3})
 		prov.connectionsMap[fmt.Sprintf("%s:%d", host, ports[2])] = 2
 
 		prov.removeConn(fmt.Sprintf("%s:%d", host, ports[1]))
@@ -196,3 +205,364 @@ func TestProvider(t *testing.T) {
 		assert.Equal(t, prov.connectionsMap[fmt.Sprintf("%s:%d", host, ports[2])], 1)
 	})
 }
+
+func TestProviderNoAvailableInstances(t *testing.T) {
+	newEmptyProvider := func() *connectionProvider {
+		prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+		prov.removeConn("127.0.0.1:5432")
+		return prov
+	}
+
+	t.Run("TestNoWait", func(t *testing.T) {
+		prov := newEmptyProvider()
+
+		conn, err := prov.nextConnection(context.Background())
+		assert.ErrorIs(t, err, ErrNoAvailableInstances)
+		assert.Nil(t, conn)
+
+		// The read lock must be released, so the connection can be added
+		require.NoError(t, prov.addConn("127.0.0.1:5433"))
+	})
+
+	t.Run("TestWaitTimeout", func(t *testing.T) {
+		prov := newEmptyProvider()
+		prov.setInstanceWaitTimeout(50 * time.Millisecond)
+
+		start := time.Now()
+		conn, err := prov.nextConnection(context.Background())
+		assert.ErrorIs(t, err, ErrNoAvailableInstances)
+		assert.Nil(t, conn)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("TestWaitContextCanceled", func(t *testing.T) {
+		prov := newEmptyProvider()
+		prov.setInstanceWaitTimeout(time.Minute)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := prov.nextConnection(ctx)
+		assert.ErrorIs(t, err, ErrNoAvailableInstances)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("TestWaitInstanceAdded", func(t *testing.T) {
+		prov := newEmptyProvider()
+		prov.setInstanceWaitTimeout(time.Minute)
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			assert.NoError(t, prov.addConn("127.0.0.1:5433"))
+		}()
+
+		conn, err := prov.nextConnection(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1:5433", conn.address)
+	})
+
+	t.Run("TestPoolMethods", func(t *testing.T) {
+		pool := &Pool{provider: newEmptyProvider()}
+		ctx := context.Background()
+
+		rows, err := pool.Query(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, ErrNoAvailableInstances)
+		assert.False(t, rows.Next())
+		assert.ErrorIs(t, rows.Err(), ErrNoAvailableInstances)
+		rows.Close()
+
+		var n int
+		assert.ErrorIs(t, pool.QueryRow(ctx, "SELECT 1").Scan(&n), ErrNoAvailableInstances)
+
+		_, err = pool.Exec(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, ErrNoAvailableInstances)
+
+		br := pool.SendBatch(ctx, &pgx.Batch{})
+		_, err = br.Exec()
+		assert.ErrorIs(t, err, ErrNoAvailableInstances)
+		assert.ErrorIs(t, br.Close(), ErrNoAvailableInstances)
+
+		_, err = pool.Acquire(ctx)
+		assert.ErrorIs(t, err, ErrNoAvailableInstances)
+
+		_, err = pool.Begin(ctx)
+		assert.ErrorIs(t, err, ErrNoAvailableInstances)
+
+		assert.ErrorIs(t, pool.Ping(ctx), ErrNoAvailableInstances)
+	})
+}
+
+func TestProviderDraining(t *testing.T) {
+	isClosed := func(pool *pgxpool.Pool) bool {
+		// Acquire fails immediately for a closed pool, for an open one it tries to connect to port 1
+		_, err := pool.Acquire(context.Background())
+		return err != nil && err.Error() == "closed pool"
+	}
+
+	t.Run("TestRemovedPoolIsClosed", func(t *testing.T) {
+		prov := newConnectionProvider(newMockPool("127.0.0.1", 1), 1)
+		require.NoError(t, prov.addConn("127.0.0.1:2"))
+		removed := prov.connsMap()["127.0.0.1:2"]
+
+		prov.removeConn("127.0.0.1:2")
+
+		assert.Eventually(t, func() bool { return prov.drainingCount() == 0 }, time.Second, 10*time.Millisecond)
+		assert.True(t, isClosed(removed))
+		assert.False(t, isClosed(prov.conns()[0]))
+	})
+
+	t.Run("TestCloseClosesDrainingPools", func(t *testing.T) {
+		prov := newConnectionProvider(newMockPool("127.0.0.1", 1), 1)
+		require.NoError(t, prov.addConn("127.0.0.1:2"))
+		removed := prov.connections[1]
+
+		// Pretend the removed instance has connections in use, so it keeps draining
+		prov.mu.Lock()
+		prov.connections = prov.connections[:1]
+		delete(prov.connectionsMap, "127.0.0.1:2")
+		prov.draining[removed] = struct{}{}
+		prov.mu.Unlock()
+
+		prov.close()
+
+		assert.True(t, isClosed(removed.pool))
+		assert.True(t, isClosed(prov.conns()[0]))
+	})
+}
+
+func TestProviderIdempotency(t *testing.T) {
+	t.Run("TestAddExisting", func(t *testing.T) {
+		prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+		initPool := prov.conns()[0]
+
+		require.NoError(t, prov.addConn("127.0.0.1:5432"))
+		assert.Len(t, prov.conns(), 1)
+		assert.Equal(t, initPool, prov.conns()[0])
+	})
+
+	t.Run("TestRemoveUnknown", func(t *testing.T) {
+		prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+		require.NoError(t, prov.addConn("127.0.0.1:5433"))
+
+		prov.removeConn("127.0.0.1:5434")
+		assert.Len(t, prov.connsMap(), 2)
+
+		prov.removeConn("127.0.0.1:5433")
+		prov.removeConn("127.0.0.1:5433")
+		assert.Len(t, prov.connsMap(), 1)
+		assert.Contains(t, prov.connsMap(), "127.0.0.1:5432")
+	})
+
+	t.Run("TestAddInvalidAddress", func(t *testing.T) {
+		prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+
+		assert.Error(t, prov.addConn("127.0.0.1"))
+		assert.Error(t, prov.addConn("127.0.0.1:port"))
+		assert.Len(t, prov.conns(), 1)
+	})
+}
+
+// latencyStrategy selects the last instance and records candidate addresses and observed latencies
+type latencyStrategy struct {
+	mu       sync.Mutex
+	selected [][]string
+	observed map[string]error
+}
+
+func (s *latencyStrategy) Select(_ context.Context, candidates []strategies.Instance) int {
+	addresses := make([]string, len(candidates))
+	for i, inst := range candidates {
+		addresses[i] = inst.Address()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.selected = append(s.selected, addresses)
+	return len(candidates) - 1
+}
+
+func (s *latencyStrategy) Observe(address string, latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.observed[address] = err
+}
+
+func (s *latencyStrategy) Type() string {
+	return "Latency"
+}
+
+func TestProviderLatencyObserver(t *testing.T) {
+	strategy := &latencyStrategy{observed: make(map[string]error)}
+
+	// Nothing listens on port 1, so every attempt fails with connection refused
+	prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+	require.NoError(t, prov.addConn("127.0.0.1:1"))
+	prov.setStrategy(strategy)
+	pool := &Pool{provider: prov}
+
+	_, err := pool.Exec(context.Background(), "SELECT 1")
+	require.Error(t, err)
+
+	require.Len(t, strategy.selected, 1)
+	assert.Equal(t, []string{"127.0.0.1:5432", "127.0.0.1:1"}, strategy.selected[0])
+	require.Contains(t, strategy.observed, "127.0.0.1:1")
+	assert.Error(t, strategy.observed["127.0.0.1:1"])
+}
+
+type ctxKey struct{}
+
+// tierStrategy selects the first instance of the tier stored in context
+type tierStrategy struct{}
+
+func (tierStrategy) Select(ctx context.Context, candidates []strategies.Instance) int {
+	tier, _ := ctx.Value(ctxKey{}).(string)
+	for i, inst := range candidates {
+		if inst.Metadata().Tier == tier && inst.Stat() != nil {
+			return i
+		}
+	}
+	return 0
+}
+
+func (tierStrategy) Type() string {
+	return "Tier"
+}
+
+func TestProviderInstanceStrategy(t *testing.T) {
+	prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+	prov.setStrategy(tierStrategy{})
+
+	prov.updateState(connState{address: "127.0.0.1:5433", currentState: InstanceStateOnline, meta: instanceMeta{tier: "hot"}})
+	require.NoError(t, prov.addConn("127.0.0.1:5433"))
+	require.NoError(t, prov.addConn("127.0.0.1:5434"))
+	prov.updateState(connState{address: "127.0.0.1:5434", currentState: InstanceStateOnline, meta: instanceMeta{tier: "cold"}})
+
+	for tier, want := range map[string]string{"hot": "127.0.0.1:5433", "cold": "127.0.0.1:5434", "": "127.0.0.1:5432"} {
+		inst, err := prov.nextConnection(context.WithValue(context.Background(), ctxKey{}, tier))
+		require.NoError(t, err)
+		assert.Equal(t, want, inst.address, tier)
+	}
+	assert.Equal(t, "Tier", prov.strategyType())
+}
+
+func TestProviderLocality(t *testing.T) {
+	opts := &poolOpts{}
+	require.NoError(t, WithLocalFailureDomain(map[string]string{"ZONE": "EU-1"})(opts))
+
+	prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+	prov.setCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Hour})
+	prov.setStrategy(opts.instanceStrategy())
+	defer prov.close()
+
+	require.NoError(t, prov.addConn("127.0.0.1:5433"))
+	require.NoError(t, prov.addConn("127.0.0.1:5434"))
+	prov.updateState(connState{address: "127.0.0.1:5432", currentState: InstanceStateOnline, meta: instanceMeta{failureDomain: map[string]string{"ZONE": "EU-2"}}})
+	prov.updateState(connState{address: "127.0.0.1:5434", currentState: InstanceStateOnline, meta: instanceMeta{failureDomain: map[string]string{"ZONE": "EU-1"}}})
+	assert.Equal(t, "Locality(RoundRobin)", prov.strategyType())
+
+	for range 3 {
+		inst, err := prov.nextConnection(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1:5434", inst.address)
+	}
+
+	// Remote instances are used only when the local one is unavailable
+	prov.connections[prov.connectionsMap["127.0.0.1:5434"]].reportResult(fmt.Errorf("dial: %w", syscall.ECONNREFUSED))
+	routed := make(map[string]bool)
+	for range 4 {
+		inst, err := prov.nextConnection(context.Background())
+		require.NoError(t, err)
+		routed[inst.address] = true
+	}
+	assert.Equal(t, map[string]bool{"127.0.0.1:5432": true, "127.0.0.1:5433": true}, routed)
+
+	topology := prov.topology()
+	require.Len(t, topology.Instances, 3)
+	assert.Equal(t, map[string]string{"ZONE": "EU-1"}, topology.Instances[2].FailureDomain)
+	assert.Nil(t, topology.Instances[1].FailureDomain)
+}
+
+func TestProviderTiers(t *testing.T) {
+	prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+	prov.setTierMaxConns(map[string]int32{"storage": 7})
+	defer prov.close()
+
+	for address, tier := range map[string]string{"127.0.0.1:5433": "router", "127.0.0.1:5434": "storage"} {
+		prov.updateState(connState{address: address, currentState: InstanceStateOnline, meta: instanceMeta{tier: tier}})
+		require.NoError(t, prov.addConn(address))
+	}
+
+	t.Run("TestTierAllowed", func(t *testing.T) {
+		assert.True(t, prov.tierAllowed("storage"))
+
+		restricted := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+		restricted.setTiers([]string{"router"})
+		defer restricted.close()
+		assert.True(t, restricted.tierAllowed("router"))
+		assert.False(t, restricted.tierAllowed("storage"))
+		assert.False(t, restricted.tierAllowed(""))
+	})
+
+	t.Run("TestTierMaxConns", func(t *testing.T) {
+		pools := prov.connsMap()
+		assert.Equal(t, int32(1), pools["127.0.0.1:5433"].Config().MaxConns)
+		assert.Equal(t, int32(7), pools["127.0.0.1:5434"].Config().MaxConns)
+	})
+
+	t.Run("TestRouting", func(t *testing.T) {
+		for tier, want := range map[string]string{"router": "127.0.0.1:5433", "storage": "127.0.0.1:5434"} {
+			ctx := WithTier(context.Background(), tier)
+			for range 3 {
+				inst, err := prov.nextConnection(ctx)
+				require.NoError(t, err)
+				assert.Equal(t, want, inst.address, tier)
+			}
+		}
+
+		// Without a tier every instance is routed
+		routed := make(map[string]bool)
+		for range 3 {
+			inst, err := prov.nextConnection(context.Background())
+			require.NoError(t, err)
+			routed[inst.address] = true
+		}
+		assert.Len(t, routed, 3)
+
+		_, err := prov.nextConnection(WithTier(context.Background(), "compute"))
+		assert.ErrorIs(t, err, ErrNoAvailableInstances)
+	})
+}
+
+func TestProviderRoutingKey(t *testing.T) {
+	prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+	prov.setStrategy(strategies.NewRendezvousStrategy(nil))
+	defer prov.close()
+
+	for _, address := range []string{"127.0.0.1:5433", "127.0.0.1:5434"} {
+		require.NoError(t, prov.addConn(address))
+	}
+
+	sticky := make(map[string]string)
+	for i := range 30 {
+		key := fmt.Sprintf("tenant-%d", i%10)
+		inst, err := prov.nextConnection(WithRoutingKey(context.Background(), key))
+		require.NoError(t, err)
+		if address, ok := sticky[key]; ok {
+			assert.Equal(t, address, inst.address, key)
+		}
+		sticky[key] = inst.address
+	}
+
+	// Keys of the removed instance move, others stay in place
+	removed := sticky["tenant-0"]
+	prov.removeConn(removed)
+	for key, address := range sticky {
+		inst, err := prov.nextConnection(WithRoutingKey(context.Background(), key))
+		require.NoError(t, err)
+		if address != removed {
+			assert.Equal(t, address, inst.address, key)
+		} else {
+			assert.NotEqual(t, removed, inst.address, key)
+		}
+	}
+}
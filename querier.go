@@ -0,0 +1,29 @@
+package picodata
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	_ Querier = (*Pool)(nil)
+	_ Querier = (*Conn)(nil)
+	_ Querier = (*Tx)(nil)
+)
+
+// Querier is the set of methods shared by [Pool], [Conn] and transactions.
+// It matches the interface expected by code generated by sqlc and pgx-based repositories,
+// so Picodata can be used wherever *pgxpool.Pool, *pgx.Conn or pgx.Tx are used.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// DBTX is an alias for [Querier] named after the interface generated by sqlc.
+type DBTX = Querier
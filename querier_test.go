@@ -0,0 +1,45 @@
+package picodata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+)
+
+// pgx.Tx is an interface, so it can only be checked at compile time
+var _ Querier = pgx.Tx(nil)
+
+func TestQuerier(t *testing.T) {
+	t.Run("TestImplementations", func(t *testing.T) {
+		implementations := []any{
+			(*Pool)(nil),
+			(*Conn)(nil),
+			(*Tx)(nil),
+			// Types Pool is a drop-in replacement for
+			(*pgxpool.Pool)(nil),
+			(*pgxpool.Conn)(nil),
+			(*pgx.Conn)(nil),
+		}
+
+		for _, impl := range implementations {
+			assert.Implements(t, (*Querier)(nil), impl)
+		}
+	})
+
+	t.Run("TestDBTXAlias", func(t *testing.T) {
+		var q Querier = &Pool{}
+		var db DBTX = q
+		assert.Equal(t, q, db)
+	})
+
+	t.Run("TestCopyFromUnreachableInstance", func(t *testing.T) {
+		var q Querier = &Pool{provider: newConnectionProvider(newMockPool("127.0.0.1", 1), 1)}
+
+		n, err := q.CopyFrom(context.Background(), pgx.Identifier{"items"}, []string{"id"}, pgx.CopyFromRows([][]any{{1}}))
+		assert.Error(t, err)
+		assert.Zero(t, n)
+	})
+}
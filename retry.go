@@ -0,0 +1,125 @@
+package picodata
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var _ RetryPolicy = (*ExponentialRetryPolicy)(nil)
+
+// RetryPolicy decides whether an operation that failed on one instance
+// should be retried on another instance chosen by the balance strategy.
+type RetryPolicy interface {
+	// Retry reports whether the operation that failed with err on the given attempt (starting from 1)
+	// should be retried, and how long to wait before the next attempt.
+	Retry(attempt int, err error) (time.Duration, bool)
+}
+
+// ExponentialRetryPolicy retries operations failed with transient errors
+// using exponential backoff with jitter.
+type ExponentialRetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It doubles with every next attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts.
+	MaxDelay time.Duration
+	// IsRetriable classifies errors. If nil, the package-level IsRetriable is used.
+	IsRetriable func(err error) bool
+}
+
+// NewExponentialRetryPolicy creates a retry policy making up to maxAttempts attempts
+// with delays growing exponentially from baseDelay up to maxDelay.
+func NewExponentialRetryPolicy(maxAttempts int, baseDelay, maxDelay time.Duration) *ExponentialRetryPolicy {
+	return &ExponentialRetryPolicy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   baseDelay,
+		MaxDelay:    maxDelay,
+	}
+}
+
+func (r *ExponentialRetryPolicy) Retry(attempt int, err error) (time.Duration, bool) {
+	if attempt >= r.MaxAttempts {
+		return 0, false
+	}
+
+	isRetriable := r.IsRetriable
+	if isRetriable == nil {
+		isRetriable = IsRetriable
+	}
+	if !isRetriable(err) {
+		return 0, false
+	}
+
+	return backoff(r.BaseDelay, r.MaxDelay, attempt), true
+}
+
+// IsRetriable reports whether err is a transient instance failure,
+// such as refused or reset connection, after which the operation may succeed on another instance.
+func IsRetriable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	return isSafeToRetry(err) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// isSafeToRetry reports whether err guarantees that nothing was sent to the instance,
+// so even a non-idempotent operation can be repeated.
+func isSafeToRetry(err error) bool {
+	var connectErr *pgconn.ConnectError
+	return pgconn.SafeToRetry(err) || errors.As(err, &connectErr)
+}
+
+// backoff returns the delay before the given attempt (starting from 1):
+// base doubled for every previous attempt, capped by maxDelay, with the upper half randomized.
+func backoff(base, maxDelay time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay > 0 && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+type noRetryKey struct{}
+
+// WithoutRetry returns a copy of ctx marking operations executed with it as non-retriable.
+func WithoutRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+func retryAllowed(ctx context.Context) bool {
+	noRetry, _ := ctx.Value(noRetryKey{}).(bool)
+	return !noRetry
+}
+
+type idempotentKey struct{}
+
+// WithIdempotent returns a copy of ctx marking Query, QueryRow and Exec executed with it as idempotent,
+// so they are retried even if the failed instance may have executed them, e.g. after a connection reset.
+// Other operations are retried only if nothing was sent to the failed instance.
+// Don't use it for DML, such as INSERT ... RETURNING, that must not be applied twice.
+func WithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+func isIdempotent(ctx context.Context) bool {
+	idempotent, _ := ctx.Value(idempotentKey{}).(bool)
+	return idempotent
+}
@@ -0,0 +1,201 @@
+package picodata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingRetryPolicy retries every error up to maxAttempts without delay and records them
+type recordingRetryPolicy struct {
+	maxAttempts int
+	errs        []error
+}
+
+func (r *recordingRetryPolicy) Retry(attempt int, err error) (time.Duration, bool) {
+	r.errs = append(r.errs, err)
+	return 0, attempt < r.maxAttempts
+}
+
+// runDroppingServer starts a server completing the startup of every connection and closing it
+// once a query is received, so the query fails after it was sent. It returns the port of the server.
+func runDroppingServer(t *testing.T) int {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveDropping(conn)
+		}
+	}()
+
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func serveDropping(conn net.Conn) {
+	defer conn.Close()
+
+	backend := pgproto3.NewBackend(conn, conn)
+	for started := false; !started; {
+		msg, err := backend.ReceiveStartupMessage()
+		if err != nil {
+			return
+		}
+
+		switch msg.(type) {
+		case *pgproto3.SSLRequest, *pgproto3.GSSEncRequest:
+			if _, err := conn.Write([]byte("N")); err != nil {
+				return
+			}
+		case *pgproto3.StartupMessage:
+			started = true
+		default:
+			return
+		}
+	}
+
+	backend.Send(&pgproto3.AuthenticationOk{})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+	if err := backend.Flush(); err != nil {
+		return
+	}
+	_, _ = backend.Receive()
+}
+
+func TestRetry(t *testing.T) {
+	t.Run("TestIsRetriable", func(t *testing.T) {
+		refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+
+		assert.True(t, IsRetriable(refused))
+		assert.True(t, IsRetriable(fmt.Errorf("wrapped: %w", syscall.ECONNRESET)))
+		assert.True(t, IsRetriable(syscall.EPIPE))
+
+		assert.False(t, IsRetriable(nil))
+		assert.False(t, IsRetriable(errors.New("syntax error")))
+		assert.False(t, IsRetriable(context.Canceled))
+		assert.False(t, IsRetriable(ErrNoAvailableInstances))
+	})
+
+	t.Run("TestBackoff", func(t *testing.T) {
+		base, maxDelay := 10*time.Millisecond, 100*time.Millisecond
+
+		for attempt, want := range map[int]time.Duration{1: 10, 2: 20, 3: 40, 4: 80, 5: 100, 50: 100} {
+			want *= time.Millisecond
+			for range 20 {
+				got := backoff(base, maxDelay, attempt)
+				assert.GreaterOrEqual(t, got, want/2, "attempt %d", attempt)
+				assert.LessOrEqual(t, got, want, "attempt %d", attempt)
+			}
+		}
+
+		assert.Zero(t, backoff(0, maxDelay, 3))
+	})
+
+	t.Run("TestExponentialRetryPolicy", func(t *testing.T) {
+		policy := NewExponentialRetryPolicy(3, time.Millisecond, 10*time.Millisecond)
+		transient := fmt.Errorf("wrapped: %w", syscall.ECONNREFUSED)
+
+		_, ok := policy.Retry(1, transient)
+		assert.True(t, ok)
+		_, ok = policy.Retry(2, transient)
+		assert.True(t, ok)
+		_, ok = policy.Retry(3, transient)
+		assert.False(t, ok, "attempts budget is exhausted")
+
+		_, ok = policy.Retry(1, errors.New("syntax error"))
+		assert.False(t, ok)
+
+		policy.IsRetriable = func(error) bool { return true }
+		_, ok = policy.Retry(1, errors.New("syntax error"))
+		assert.True(t, ok)
+	})
+
+	t.Run("TestWithoutRetry", func(t *testing.T) {
+		assert.True(t, retryAllowed(context.Background()))
+		assert.False(t, retryAllowed(WithoutRetry(context.Background())))
+	})
+
+	t.Run("TestWithIdempotent", func(t *testing.T) {
+		assert.False(t, isIdempotent(context.Background()))
+		assert.True(t, isIdempotent(WithIdempotent(context.Background())))
+	})
+
+	// Nothing listens on ports 1 and 2, so every attempt fails with connection refused
+	newUnreachablePool := func(policy RetryPolicy) *Pool {
+		prov := newConnectionProvider(newMockPool("127.0.0.1", 1), 1)
+		require.NoError(t, prov.addConn("127.0.0.1:2"))
+		return &Pool{provider: prov, retryPolicy: policy}
+	}
+
+	t.Run("TestRetryOnAnotherInstance", func(t *testing.T) {
+		policy := &recordingRetryPolicy{maxAttempts: 3}
+		pool := newUnreachablePool(policy)
+
+		_, err := pool.Exec(context.Background(), "SELECT 1")
+		require.Error(t, err)
+		require.Len(t, policy.errs, 3)
+
+		// Every retry goes to another instance
+		assert.Contains(t, policy.errs[0].Error(), "127.0.0.1:1")
+		assert.Contains(t, policy.errs[1].Error(), "127.0.0.1:2")
+		assert.Contains(t, policy.errs[2].Error(), "127.0.0.1:1")
+
+		metrics := pool.Metrics()
+		assert.Equal(t, uint64(2), metrics.Retries)
+		assert.Equal(t, map[string]uint64{"127.0.0.1:1": 2, "127.0.0.1:2": 1}, metrics.QueriesRouted)
+	})
+
+	t.Run("TestRetryDisabledByContext", func(t *testing.T) {
+		policy := &recordingRetryPolicy{maxAttempts: 3}
+		pool := newUnreachablePool(policy)
+
+		_, err := pool.Query(WithoutRetry(context.Background()), "SELECT 1")
+		require.Error(t, err)
+		assert.Empty(t, policy.errs)
+	})
+
+	t.Run("TestQueryRowRetried", func(t *testing.T) {
+		policy := &recordingRetryPolicy{maxAttempts: 2}
+		pool := newUnreachablePool(policy)
+
+		var n int
+		assert.Error(t, pool.QueryRow(context.Background(), "SELECT 1").Scan(&n))
+		assert.Len(t, policy.errs, 2)
+	})
+
+	t.Run("TestQueryNotRetriedAfterSent", func(t *testing.T) {
+		port := runDroppingServer(t)
+		newDroppingPool := func(policy RetryPolicy) *Pool {
+			prov := newConnectionProvider(newMockPool("127.0.0.1", port), 1)
+			t.Cleanup(prov.close)
+			return &Pool{provider: prov, retryPolicy: policy}
+		}
+
+		// The query may have been executed before the connection was lost, so it must not run twice
+		policy := &recordingRetryPolicy{maxAttempts: 2}
+		_, err := newDroppingPool(policy).Query(context.Background(), "INSERT INTO t VALUES (1) RETURNING id")
+		require.Error(t, err)
+		assert.Empty(t, policy.errs)
+
+		policy = &recordingRetryPolicy{maxAttempts: 2}
+		_, err = newDroppingPool(policy).Query(WithIdempotent(context.Background()), "SELECT 1")
+		require.Error(t, err)
+		assert.Len(t, policy.errs, 2)
+	})
+}
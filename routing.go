@@ -0,0 +1,112 @@
+package picodata
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/picodata/picodata-go/strategies"
+)
+
+// RoutingMode defines whether operations are routed to replicaset leaders (masters) or replicas.
+type RoutingMode int
+
+const (
+	// RoutingModeAny routes operations to any instance.
+	RoutingModeAny RoutingMode = iota
+	// RoutingModePreferLeader routes operations to replicaset leaders,
+	// falling back to replicas when none of the leaders is available.
+	RoutingModePreferLeader
+	// RoutingModeLeaderOnly routes operations to replicaset leaders only.
+	// If none of them is available, operations fail with ErrNoAvailableInstances.
+	RoutingModeLeaderOnly
+	// RoutingModePreferReplica routes operations to replicas,
+	// falling back to leaders when none of the replicas is available.
+	RoutingModePreferReplica
+)
+
+func (m RoutingMode) String() string {
+	switch m {
+	case RoutingModeAny:
+		return "any"
+	case RoutingModePreferLeader:
+		return "prefer leader"
+	case RoutingModeLeaderOnly:
+		return "leader only"
+	case RoutingModePreferReplica:
+		return "prefer replica"
+	default:
+		return "unknown"
+	}
+}
+
+func (m RoutingMode) validate() error {
+	if m < RoutingModeAny || m > RoutingModePreferReplica {
+		return fmt.Errorf("unknown routing mode %d", m)
+	}
+	return nil
+}
+
+type routingModeKey struct{}
+
+// WithRoutingMode returns a copy of ctx routing operations executed with it according to mode.
+// It takes precedence over the pool default, see [WithDefaultRoutingMode].
+func WithRoutingMode(ctx context.Context, mode RoutingMode) context.Context {
+	return context.WithValue(ctx, routingModeKey{}, mode)
+}
+
+// WithRoutingKey returns a copy of ctx carrying the key operations are routed by, e.g. a tenant id.
+// Operations with the same key are routed to the same instance by strategies taking it into account,
+// such as [strategies.NewRendezvousStrategy], and as usual by other strategies.
+func WithRoutingKey(ctx context.Context, key string) context.Context {
+	return strategies.WithRoutingKey(ctx, key)
+}
+
+func routingModeFromContext(ctx context.Context) (RoutingMode, bool) {
+	mode, ok := ctx.Value(routingModeKey{}).(RoutingMode)
+	return mode, ok
+}
+
+// routingContext returns ctx carrying the routing mode of an operation: the one set with WithRoutingMode,
+// the pool default or opMode, in that order.
+func (p *Pool) routingContext(ctx context.Context, opMode RoutingMode) context.Context {
+	if _, ok := routingModeFromContext(ctx); ok {
+		return ctx
+	}
+
+	mode := opMode
+	if p.routingMode != nil {
+		mode = *p.routingMode
+	}
+	// Instances are not filtered without a routing mode, so ctx is not copied
+	if mode == RoutingModeAny {
+		return ctx
+	}
+
+	return WithRoutingMode(ctx, mode)
+}
+
+// instancesForMode returns instances operations may be routed to in mode.
+// The slice is copied only if some of the instances are excluded.
+func instancesForMode(instances []*instance, mode RoutingMode) []*instance {
+	var leader bool
+	switch mode {
+	case RoutingModePreferLeader, RoutingModeLeaderOnly:
+		leader = true
+	case RoutingModePreferReplica:
+		leader = false
+	default:
+		return instances
+	}
+
+	candidates := make([]*instance, 0, len(instances))
+	for _, inst := range instances {
+		if inst.meta.leader == leader {
+			candidates = append(candidates, inst)
+		}
+	}
+
+	if len(candidates) == 0 && mode != RoutingModeLeaderOnly {
+		return instances
+	}
+	return candidates
+}
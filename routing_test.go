@@ -0,0 +1,89 @@
+package picodata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutingMode(t *testing.T) {
+	t.Run("TestString", func(t *testing.T) {
+		assert.Equal(t, "prefer leader", RoutingModePreferLeader.String())
+		assert.Equal(t, "unknown", RoutingMode(100).String())
+		assert.NoError(t, RoutingModePreferReplica.validate())
+		assert.Error(t, RoutingMode(-1).validate())
+	})
+
+	t.Run("TestInstancesForMode", func(t *testing.T) {
+		leader := &instance{address: "leader", meta: instanceMeta{leader: true}}
+		replica := &instance{address: "replica"}
+		all := []*instance{leader, replica}
+
+		assert.Equal(t, all, instancesForMode(all, RoutingModeAny))
+		assert.Equal(t, []*instance{leader}, instancesForMode(all, RoutingModePreferLeader))
+		assert.Equal(t, []*instance{leader}, instancesForMode(all, RoutingModeLeaderOnly))
+		assert.Equal(t, []*instance{replica}, instancesForMode(all, RoutingModePreferReplica))
+
+		// Preferences fall back to other instances
+		replicas := []*instance{replica}
+		assert.Equal(t, replicas, instancesForMode(replicas, RoutingModePreferLeader))
+		assert.Empty(t, instancesForMode(replicas, RoutingModeLeaderOnly))
+		leaders := []*instance{leader}
+		assert.Equal(t, leaders, instancesForMode(leaders, RoutingModePreferReplica))
+	})
+
+	t.Run("TestRoutingContext", func(t *testing.T) {
+		pool := &Pool{}
+		mode, ok := routingModeFromContext(pool.routingContext(context.Background(), RoutingModePreferLeader))
+		assert.True(t, ok)
+		assert.Equal(t, RoutingModePreferLeader, mode)
+
+		_, ok = routingModeFromContext(pool.routingContext(context.Background(), RoutingModeAny))
+		assert.False(t, ok)
+
+		// The pool default overrides the one of the operation
+		replica := RoutingModePreferReplica
+		pool.routingMode = &replica
+		mode, _ = routingModeFromContext(pool.routingContext(context.Background(), RoutingModePreferLeader))
+		assert.Equal(t, RoutingModePreferReplica, mode)
+
+		// The mode of the operation overrides the pool default
+		ctx := WithRoutingMode(context.Background(), RoutingModeLeaderOnly)
+		mode, _ = routingModeFromContext(pool.routingContext(ctx, RoutingModeAny))
+		assert.Equal(t, RoutingModeLeaderOnly, mode)
+	})
+}
+
+func TestProviderRoutingMode(t *testing.T) {
+	prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+	defer prov.close()
+
+	prov.updateState(connState{address: "127.0.0.1:5432", currentState: InstanceStateOnline, meta: instanceMeta{replicasetName: "r1", leader: true}})
+	prov.updateState(connState{address: "127.0.0.1:5433", currentState: InstanceStateOnline, meta: instanceMeta{replicasetName: "r1"}})
+	require.NoError(t, prov.addConn("127.0.0.1:5433"))
+
+	for mode, want := range map[RoutingMode]string{
+		RoutingModePreferLeader:  "127.0.0.1:5432",
+		RoutingModeLeaderOnly:    "127.0.0.1:5432",
+		RoutingModePreferReplica: "127.0.0.1:5433",
+	} {
+		for range 3 {
+			inst, err := prov.nextConnection(WithRoutingMode(context.Background(), mode))
+			require.NoError(t, err)
+			assert.Equal(t, want, inst.address, mode.String())
+		}
+	}
+
+	// Leadership moves to another instance
+	prov.updateState(connState{address: "127.0.0.1:5432", currentState: InstanceStateOnline, meta: instanceMeta{replicasetName: "r1"}})
+	_, err := prov.nextConnection(WithRoutingMode(context.Background(), RoutingModeLeaderOnly))
+	assert.ErrorIs(t, err, ErrNoAvailableInstances)
+
+	prov.updateState(connState{address: "127.0.0.1:5433", currentState: InstanceStateOnline, meta: instanceMeta{replicasetName: "r1", leader: true}})
+	inst, err := prov.nextConnection(WithRoutingMode(context.Background(), RoutingModeLeaderOnly))
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:5433", inst.address)
+	assert.True(t, prov.topology().Instances[1].Leader)
+}
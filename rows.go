@@ -0,0 +1,116 @@
+package picodata
+
+import (
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	_ pgx.Row          = (*poolRow)(nil)
+	_ pgx.Rows         = (*tracedRows)(nil)
+	_ pgx.BatchResults = (*poolBatchResults)(nil)
+)
+
+// poolRow is a pgx.Row built on top of pgx.Rows returned by Pool.Query.
+// The connection is returned to the instance pool when Scan is called.
+type poolRow struct {
+	rows pgx.Rows
+	err  error
+}
+
+func (r *poolRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	defer r.rows.Close()
+
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+	r.rows.Close()
+
+	return r.rows.Err()
+}
+
+// tracedRows ends the span of the query once the rows are read or closed,
+// so the span covers reading them and records the error they were read with.
+type tracedRows struct {
+	pgx.Rows
+	span trace.Span
+}
+
+// newTracedRows returns rows ending span. Span is ended at once if it's not recorded.
+func newTracedRows(rows pgx.Rows, span trace.Span) pgx.Rows {
+	if !span.IsRecording() {
+		span.End()
+		return rows
+	}
+
+	return &tracedRows{Rows: rows, span: span}
+}
+
+func (r *tracedRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	// NOTE: rows are closed once Next returns false
+	r.end()
+	return false
+}
+
+func (r *tracedRows) Close() {
+	r.Rows.Close()
+	r.end()
+}
+
+func (r *tracedRows) end() {
+	if r.span == nil {
+		return
+	}
+	endSpan(r.span, r.Rows.Err())
+	r.span = nil
+}
+
+// poolBatchResults releases the acquired connection and ends the span when the batch results are closed.
+type poolBatchResults struct {
+	br   pgx.BatchResults
+	conn *pgxpool.Conn
+	// span is nil once it's ended
+	span trace.Span
+}
+
+func (br *poolBatchResults) Exec() (pgconn.CommandTag, error) {
+	return br.br.Exec()
+}
+
+func (br *poolBatchResults) Query() (pgx.Rows, error) {
+	return br.br.Query()
+}
+
+func (br *poolBatchResults) QueryRow() pgx.Row {
+	return br.br.QueryRow()
+}
+
+func (br *poolBatchResults) Close() error {
+	err := br.br.Close()
+	if br.conn != nil {
+		br.conn.Release()
+		br.conn = nil
+	}
+	if br.span != nil {
+		endSpan(br.span, err)
+		br.span = nil
+	}
+
+	return err
+}
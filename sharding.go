@@ -0,0 +1,379 @@
+package picodata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/picodata/picodata-go/logger"
+)
+
+const (
+	// DefaultBucketCount is the number of buckets of a tier unless it is configured otherwise.
+	DefaultBucketCount = 3000
+	// defaultDistributionTTL is how long table distribution and tier bucket counts are cached
+	defaultDistributionTTL = time.Minute
+
+	tableDistributionQuery = `SELECT distribution FROM _pico_table WHERE name = $1;`
+	tierBucketCountQuery   = `SELECT bucket_count FROM _pico_tier WHERE name = $1;`
+)
+
+// BucketResolver tells which replicaset stores a bucket. Picodata doesn't expose bucket ownership
+// through SQL and the pool doesn't derive it from the cluster, so it's up to the application to keep it,
+// e.g. from the cluster configuration.
+type BucketResolver interface {
+	// Replicaset returns the name of the replicaset of tier storing bucketID
+	// or false if it's unknown.
+	Replicaset(tier string, bucketID uint64) (string, bool)
+}
+
+// BucketResolverFunc is an adapter to use an ordinary function as a BucketResolver.
+type BucketResolverFunc func(tier string, bucketID uint64) (string, bool)
+
+func (f BucketResolverFunc) Replicaset(tier string, bucketID uint64) (string, bool) {
+	return f(tier, bucketID)
+}
+
+// BucketID computes the id of the bucket storing a row with sharding key values key the way Picodata does:
+// the values are converted to strings, concatenated and hashed with MurmurHash3.
+// Strings, byte slices, booleans, integers, floats and fmt.Stringer values, such as UUIDs, are supported.
+// It's meant for BucketResolver implementations, e.g. to check which buckets of a tier are known.
+func BucketID(key []any, bucketCount uint64) (uint64, error) {
+	const op = "sharding: BucketID"
+
+	if len(key) == 0 {
+		return 0, fmt.Errorf("%s: sharding key is empty", op)
+	}
+	if bucketCount == 0 {
+		return 0, fmt.Errorf("%s: bucket count must be positive", op)
+	}
+
+	var b strings.Builder
+	for i, value := range key {
+		if err := writeKeyValue(&b, value); err != nil {
+			return 0, fmt.Errorf("%s: sharding key value %d: %w", op, i, err)
+		}
+	}
+
+	return uint64(murmur3([]byte(b.String())))%bucketCount + 1, nil
+}
+
+func writeKeyValue(b *strings.Builder, value any) error {
+	switch v := value.(type) {
+	case string:
+		b.WriteString(v)
+	case []byte:
+		b.Write(v)
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case int:
+		b.WriteString(strconv.FormatInt(int64(v), 10))
+	case int8:
+		b.WriteString(strconv.FormatInt(int64(v), 10))
+	case int16:
+		b.WriteString(strconv.FormatInt(int64(v), 10))
+	case int32:
+		b.WriteString(strconv.FormatInt(int64(v), 10))
+	case int64:
+		b.WriteString(strconv.FormatInt(v, 10))
+	case uint:
+		b.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint8:
+		b.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint16:
+		b.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint32:
+		b.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint64:
+		b.WriteString(strconv.FormatUint(v, 10))
+	case float32:
+		b.WriteString(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	case float64:
+		b.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	case fmt.Stringer:
+		b.WriteString(v.String())
+	case nil:
+		return fmt.Errorf("is nil")
+	default:
+		return fmt.Errorf("has unsupported type %T", value)
+	}
+
+	return nil
+}
+
+// tableDistribution describes how rows of a table are distributed across the cluster.
+type tableDistribution struct {
+	// global tables are stored by every instance
+	global bool
+	tier   string
+	// shardingKey is nil if the table is sharded by a field storing bucket ids explicitly
+	shardingKey []string
+}
+
+// parseDistribution decodes a fetched table distribution, e.g. "Global"
+// or {"ShardedImplicitly": [["id"], "murmur3", "default"]}.
+func parseDistribution(fetchedDistribution []byte) (tableDistribution, error) {
+	var unit string
+	if err := json.Unmarshal(fetchedDistribution, &unit); err == nil {
+		if unit == "Global" {
+			return tableDistribution{global: true}, nil
+		}
+		return tableDistribution{}, fmt.Errorf("unknown distribution %q", unit)
+	}
+
+	var variants map[string]json.RawMessage
+	if err := json.Unmarshal(fetchedDistribution, &variants); err != nil {
+		return tableDistribution{}, fmt.Errorf("must be a string or an object: %w", err)
+	}
+
+	for variant, fields := range variants {
+		switch variant {
+		case "Global":
+			return tableDistribution{global: true}, nil
+		case "ShardedImplicitly":
+			var sharded struct {
+				ShardingKey []string `json:"sharding_key"`
+				Tier        string   `json:"tier"`
+			}
+			var tuple []json.RawMessage
+			if err := json.Unmarshal(fields, &tuple); err == nil && len(tuple) == 3 {
+				if err := json.Unmarshal(tuple[0], &sharded.ShardingKey); err != nil {
+					return tableDistribution{}, fmt.Errorf("sharding key: %w", err)
+				}
+				if err := json.Unmarshal(tuple[2], &sharded.Tier); err != nil {
+					return tableDistribution{}, fmt.Errorf("tier: %w", err)
+				}
+			} else if err := json.Unmarshal(fields, &sharded); err != nil {
+				return tableDistribution{}, fmt.Errorf("%s: %w", variant, err)
+			}
+			if len(sharded.ShardingKey) == 0 {
+				return tableDistribution{}, fmt.Errorf("sharding key is empty")
+			}
+			return tableDistribution{tier: sharded.Tier, shardingKey: sharded.ShardingKey}, nil
+		case "ShardedByField":
+			var sharded struct {
+				Tier string `json:"tier"`
+			}
+			var tuple []json.RawMessage
+			if err := json.Unmarshal(fields, &tuple); err == nil && len(tuple) == 2 {
+				if err := json.Unmarshal(tuple[1], &sharded.Tier); err != nil {
+					return tableDistribution{}, fmt.Errorf("tier: %w", err)
+				}
+			} else if err := json.Unmarshal(fields, &sharded); err != nil {
+				return tableDistribution{}, fmt.Errorf("%s: %w", variant, err)
+			}
+			return tableDistribution{tier: sharded.Tier}, nil
+		default:
+			return tableDistribution{}, fmt.Errorf("unknown distribution %q", variant)
+		}
+	}
+
+	return tableDistribution{}, fmt.Errorf("is empty")
+}
+
+type cachedDistribution struct {
+	distribution tableDistribution
+	loadedAt     time.Time
+}
+
+type cachedBucketCount struct {
+	bucketCount uint64
+	loadedAt    time.Time
+}
+
+// shardingRouter routes operations on a sharding key to the instances storing it.
+type shardingRouter struct {
+	provider *connectionProvider
+	// resolver is nil if bucket ownership is unknown
+	resolver BucketResolver
+	ttl      time.Duration
+
+	mu sync.Mutex
+	// Key: table name
+	tables map[string]cachedDistribution
+	// Key: tier name
+	bucketCounts map[string]cachedBucketCount
+}
+
+func newShardingRouter(provider *connectionProvider, resolver BucketResolver) *shardingRouter {
+	return &shardingRouter{
+		provider:     provider,
+		resolver:     resolver,
+		ttl:          defaultDistributionTTL,
+		tables:       make(map[string]cachedDistribution),
+		bucketCounts: make(map[string]cachedBucketCount),
+	}
+}
+
+// route returns a copy of ctx routing operations to the instances storing rows of table with sharding key key.
+// Operations on global tables are routed as usual. Without a resolver, or if it doesn't know the bucket,
+// operations are routed to the tier of the table. The tier is only preferred, so pools that don't route
+// to it, e.g. created with WithTiers("router"), let another instance forward the operation.
+func (r *shardingRouter) route(ctx context.Context, table string, key []any) (context.Context, error) {
+	const op = "sharding: route"
+
+	distribution, err := r.distribution(ctx, table)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if distribution.global {
+		return ctx, nil
+	}
+
+	if distribution.tier != "" {
+		ctx = withPreferredTier(ctx, distribution.tier)
+	}
+	if distribution.shardingKey == nil {
+		return ctx, nil
+	}
+
+	if len(key) != len(distribution.shardingKey) {
+		return nil, fmt.Errorf("%s: table %s is sharded by %d columns (%s), but key has %d values",
+			op, table, len(distribution.shardingKey), strings.Join(distribution.shardingKey, ", "), len(key))
+	}
+	if r.resolver == nil {
+		return ctx, nil
+	}
+
+	bucket, err := BucketID(key, r.bucketCount(ctx, distribution.tier))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if replicaset, ok := r.resolver.Replicaset(distribution.tier, bucket); ok {
+		ctx = withReplicaset(ctx, replicaset)
+	}
+
+	return ctx, nil
+}
+
+// distribution returns the distribution of table, loading it from _pico_table if it's not cached.
+func (r *shardingRouter) distribution(ctx context.Context, table string) (tableDistribution, error) {
+	r.mu.Lock()
+	cached, ok := r.tables[table]
+	r.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < r.ttl {
+		return cached.distribution, nil
+	}
+
+	inst, err := r.provider.nextConnection(ctx)
+	if err != nil {
+		return tableDistribution{}, err
+	}
+
+	var fetchedDistribution []byte // contains a JSON string or object
+	if err := inst.pool.QueryRow(ctx, tableDistributionQuery, table).Scan(&fetchedDistribution); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return tableDistribution{}, fmt.Errorf("table %s not found", table)
+		}
+		return tableDistribution{}, err
+	}
+
+	distribution, err := parseDistribution(fetchedDistribution)
+	if err != nil {
+		return tableDistribution{}, fmt.Errorf("table %s distribution %w", table, err)
+	}
+
+	r.mu.Lock()
+	r.tables[table] = cachedDistribution{distribution: distribution, loadedAt: time.Now()}
+	r.mu.Unlock()
+
+	return distribution, nil
+}
+
+// bucketCount returns the number of buckets of tier, loading it from _pico_tier if it's not cached.
+// DefaultBucketCount is returned if the cluster doesn't report it.
+func (r *shardingRouter) bucketCount(ctx context.Context, tier string) uint64 {
+	const op = "sharding: bucketCount"
+
+	r.mu.Lock()
+	cached, ok := r.bucketCounts[tier]
+	r.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < r.ttl {
+		return cached.bucketCount
+	}
+
+	bucketCount := uint64(DefaultBucketCount)
+	inst, err := r.provider.nextConnection(ctx)
+	if err == nil {
+		var fetchedBucketCount int64
+		err = inst.pool.QueryRow(ctx, tierBucketCountQuery, tier).Scan(&fetchedBucketCount)
+		if err == nil && fetchedBucketCount > 0 {
+			bucketCount = uint64(fetchedBucketCount)
+		}
+	}
+	if err != nil {
+		// NOTE: older Picodata versions have the same number of buckets in every tier
+		logger.LogFields(r.provider.logger, logger.LevelWarn, "failed to get tier bucket count, using the default one",
+			logger.String(logger.KeyOp, op), logger.String("tier", tier), logger.Any("bucket_count", bucketCount), logger.Err(err))
+	}
+
+	r.mu.Lock()
+	r.bucketCounts[tier] = cachedBucketCount{bucketCount: bucketCount, loadedAt: time.Now()}
+	r.mu.Unlock()
+
+	return bucketCount
+}
+
+type replicasetKey struct{}
+
+func withReplicaset(ctx context.Context, replicaset string) context.Context {
+	return context.WithValue(ctx, replicasetKey{}, replicaset)
+}
+
+func replicasetFromContext(ctx context.Context) (string, bool) {
+	replicaset, ok := ctx.Value(replicasetKey{}).(string)
+	return replicaset, ok
+}
+
+// instancesOfReplicaset returns instances of replicaset or all instances if none of them is available,
+// so the operation is forwarded by another instance.
+func instancesOfReplicaset(instances []*instance, replicaset string) []*instance {
+	candidates := make([]*instance, 0, len(instances))
+	for _, inst := range instances {
+		if inst.meta.replicasetName == replicaset {
+			candidates = append(candidates, inst)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return instances
+	}
+	return candidates
+}
+
+// QueryByKey is the same as Query, but routes the query by the table of sharding key values key.
+// Values of key must be in the order of the table sharding key columns.
+//
+// The query is sent to an instance of the table tier, if the pool routes to it, and forwarded
+// inside the cluster to the replicaset storing the key. The pool doesn't know bucket ownership,
+// so the query is sent straight to that replicaset only if the resolver set with [WithBucketResolver]
+// knows the bucket. Queries on global tables are routed as usual.
+//
+//	rows, err := pool.QueryByKey(ctx, "orders", []any{customerID}, "SELECT * FROM orders WHERE customer_id = $1", customerID)
+func (p *Pool) QueryByKey(ctx context.Context, table string, key []any, sql string, args ...any) (pgx.Rows, error) {
+	ctx, err := p.router.route(ctx, table, key)
+	if err != nil {
+		return errRows{err: err}, err
+	}
+
+	return p.Query(ctx, sql, args...)
+}
+
+// ExecByKey is the same as Exec, but routes the statement by the table of sharding key values key.
+// See [Pool.QueryByKey] for details.
+func (p *Pool) ExecByKey(ctx context.Context, table string, key []any, sql string, args ...any) (pgconn.CommandTag, error) {
+	ctx, err := p.router.route(ctx, table, key)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+
+	return p.Exec(ctx, sql, args...)
+}
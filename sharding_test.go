@@ -0,0 +1,225 @@
+package picodata
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stringerKey struct{}
+
+func (stringerKey) String() string {
+	return "key"
+}
+
+func TestMurmur3(t *testing.T) {
+	for input, want := range map[string]uint32{
+		"":      0,
+		"hello": 0x248bfa47,
+		"The quick brown fox jumps over the lazy dog": 0x2e4ff723,
+	} {
+		assert.Equal(t, want, murmur3([]byte(input)), input)
+	}
+}
+
+func TestBucketID(t *testing.T) {
+	t.Run("TestKeyValues", func(t *testing.T) {
+		// Values are hashed as the concatenation of their string representations
+		want, err := BucketID([]any{"42true1.5key"}, DefaultBucketCount)
+		require.NoError(t, err)
+		got, err := BucketID([]any{int64(42), true, 1.5, stringerKey{}}, DefaultBucketCount)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+
+		for _, key := range [][]any{{42}, {int8(42)}, {uint16(42)}, {uint64(42)}, {[]byte("42")}, {float32(42)}} {
+			got, err := BucketID(key, DefaultBucketCount)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(murmur3([]byte("42")))%DefaultBucketCount+1, got, key)
+		}
+	})
+
+	t.Run("TestRange", func(t *testing.T) {
+		for i := range 1000 {
+			got, err := BucketID([]any{i}, 10)
+			require.NoError(t, err)
+			assert.GreaterOrEqual(t, got, uint64(1))
+			assert.LessOrEqual(t, got, uint64(10))
+		}
+	})
+
+	t.Run("TestInvalid", func(t *testing.T) {
+		for name, key := range map[string][]any{"Empty": nil, "Nil": {nil}, "Unsupported": {struct{}{}}} {
+			_, err := BucketID(key, DefaultBucketCount)
+			assert.Error(t, err, name)
+		}
+		_, err := BucketID([]any{1}, 0)
+		assert.Error(t, err)
+	})
+}
+
+func TestParseDistribution(t *testing.T) {
+	for fetched, want := range map[string]tableDistribution{
+		`"Global"`:         {global: true},
+		`{"Global": null}`: {global: true},
+		`{"ShardedImplicitly": [["id", "name"], "murmur3", "default"]}`:                            {tier: "default", shardingKey: []string{"id", "name"}},
+		`{"ShardedImplicitly": {"sharding_key": ["id"], "sharding_fn": "murmur3", "tier": "hot"}}`: {tier: "hot", shardingKey: []string{"id"}},
+		`{"ShardedByField": ["bucket_id", "default"]}`:                                             {tier: "default"},
+	} {
+		got, err := parseDistribution([]byte(fetched))
+		require.NoError(t, err, fetched)
+		assert.Equal(t, want, got, fetched)
+	}
+
+	for _, fetched := range []string{``, `"Local"`, `{}`, `{"Unknown": []}`, `{"ShardedImplicitly": [[], "murmur3", "default"]}`} {
+		_, err := parseDistribution([]byte(fetched))
+		assert.Error(t, err, fetched)
+	}
+}
+
+func TestShardingRouter(t *testing.T) {
+	prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+	defer prov.close()
+
+	for address, meta := range map[string]instanceMeta{
+		"127.0.0.1:5432": {replicasetName: "r1", tier: "default", leader: true},
+		"127.0.0.1:5433": {replicasetName: "r2", tier: "default", leader: true},
+		"127.0.0.1:5434": {replicasetName: "r2", tier: "default"},
+		"127.0.0.1:5435": {replicasetName: "r3", tier: "hot"},
+	} {
+		prov.updateState(connState{address: address, currentState: InstanceStateOnline, meta: meta})
+		require.NoError(t, prov.addConn(address))
+	}
+
+	// Buckets up to 1500 are stored by r1, the rest by r2
+	resolver := BucketResolverFunc(func(tier string, bucketID uint64) (string, bool) {
+		if tier != "default" {
+			return "", false
+		}
+		if bucketID <= DefaultBucketCount/2 {
+			return "r1", true
+		}
+		return "r2", true
+	})
+	router := newShardingRouter(prov, resolver)
+	// Cached metadata is used instead of querying the cluster
+	now := time.Now()
+	router.tables["orders"] = cachedDistribution{distribution: tableDistribution{tier: "default", shardingKey: []string{"id"}}, loadedAt: now}
+	router.tables["items"] = cachedDistribution{distribution: tableDistribution{tier: "hot", shardingKey: []string{"id"}}, loadedAt: now}
+	router.tables["regions"] = cachedDistribution{distribution: tableDistribution{global: true}, loadedAt: now}
+	router.bucketCounts["default"] = cachedBucketCount{bucketCount: DefaultBucketCount, loadedAt: now}
+	router.bucketCounts["hot"] = cachedBucketCount{bucketCount: DefaultBucketCount, loadedAt: now}
+
+	t.Run("TestReplicaset", func(t *testing.T) {
+		for i := range 20 {
+			bucket, err := BucketID([]any{i}, DefaultBucketCount)
+			require.NoError(t, err)
+			want := "r1"
+			if bucket > DefaultBucketCount/2 {
+				want = "r2"
+			}
+
+			ctx, err := router.route(context.Background(), "orders", []any{i})
+			require.NoError(t, err)
+			replicaset, ok := replicasetFromContext(ctx)
+			require.True(t, ok)
+			assert.Equal(t, want, replicaset)
+
+			inst, err := prov.nextConnection(WithRoutingMode(ctx, RoutingModeLeaderOnly))
+			require.NoError(t, err)
+			assert.Equal(t, want, inst.meta.replicasetName)
+		}
+	})
+
+	t.Run("TestTier", func(t *testing.T) {
+		ctx, err := router.route(context.Background(), "items", []any{1})
+		require.NoError(t, err)
+		_, ok := replicasetFromContext(ctx)
+		assert.False(t, ok)
+
+		inst, err := prov.nextConnection(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1:5435", inst.address)
+	})
+
+	t.Run("TestGlobal", func(t *testing.T) {
+		ctx, err := router.route(context.Background(), "regions", nil)
+		require.NoError(t, err)
+		_, ok := tierFromContext(ctx)
+		assert.False(t, ok)
+	})
+
+	t.Run("TestInvalidKey", func(t *testing.T) {
+		_, err := router.route(context.Background(), "orders", []any{1, 2})
+		assert.Error(t, err)
+	})
+
+	t.Run("TestTierNotRouted", func(t *testing.T) {
+		// A pool routing to routers only lets them forward operations on storage tables
+		routers := newConnectionProvider(newMockPool("127.0.0.1", 5436), 1)
+		defer routers.close()
+		routers.updateState(connState{address: "127.0.0.1:5436", currentState: InstanceStateOnline, meta: instanceMeta{tier: "router"}})
+		routers.setTiers([]string{"router"})
+
+		routersRouter := newShardingRouter(routers, nil)
+		routersRouter.tables["orders"] = router.tables["orders"]
+
+		ctx, err := routersRouter.route(context.Background(), "orders", []any{1})
+		require.NoError(t, err)
+		inst, err := routers.nextConnection(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1:5436", inst.address)
+	})
+
+	t.Run("TestUnavailableReplicaset", func(t *testing.T) {
+		candidates := []*instance{prov.connections[0]}
+		assert.Equal(t, candidates, instancesOfReplicaset(candidates, "r2"))
+		assert.Equal(t, candidates, instancesPreferringTier(candidates, "hot"))
+	})
+}
+
+func TestIntegrationBucketID(t *testing.T) {
+	pool := newIntegrationPool(t, WithDisableTopologyManaging())
+	ctx := context.Background()
+
+	bucketCount := pool.router.bucketCount(ctx, "default")
+
+	for _, tc := range []struct {
+		name    string
+		colType string
+		keys    []any
+	}{
+		{name: "Int", colType: "INT", keys: []any{int64(0), int64(42), int64(-7), int64(1) << 40}},
+		{name: "String", colType: "TEXT", keys: []any{"", "hello", "привет"}},
+		{name: "Float", colType: "DOUBLE", keys: []any{1.5, 2.0, -0.25}},
+		{name: "Bool", colType: "BOOLEAN", keys: []any{true, false}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			table := "bucket_id_" + strings.ToLower(tc.name)
+			_, err := pool.Exec(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INT PRIMARY KEY, k %s) DISTRIBUTED BY (k)", table, tc.colType))
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				_, err := pool.Exec(ctx, "DROP TABLE IF EXISTS "+table)
+				assert.NoError(t, err)
+			})
+
+			for id, key := range tc.keys {
+				_, err := pool.Exec(ctx, fmt.Sprintf("INSERT INTO %s VALUES ($1, $2)", table), id, key)
+				require.NoError(t, err)
+
+				// bucket_id is a hidden column computed by Picodata on insert
+				var want uint64
+				err = pool.QueryRow(ctx, fmt.Sprintf(`SELECT "bucket_id" FROM %s WHERE id = $1`, table), id).Scan(&want)
+				require.NoError(t, err)
+
+				got, err := BucketID([]any{key}, bucketCount)
+				require.NoError(t, err)
+				assert.Equal(t, want, got, key)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package picodata
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Stat is a snapshot of Pool statistics aggregated across all routed instances.
+// Its methods mirror [pgxpool.Stat].
+type Stat struct {
+	instances               int
+	acquireCount            int64
+	acquireDuration         time.Duration
+	acquiredConns           int32
+	canceledAcquireCount    int64
+	constructingConns       int32
+	emptyAcquireCount       int64
+	idleConns               int32
+	maxConns                int32
+	totalConns              int32
+	newConnsCount           int64
+	maxLifetimeDestroyCount int64
+	maxIdleDestroyCount     int64
+}
+
+// Stat returns statistics aggregated across the pools of all routed instances.
+// Cumulative counters also include the pools of removed instances, so they never decrease.
+func (p *Pool) Stat() *Stat {
+	return p.provider.stat()
+}
+
+// InstanceStats returns statistics of every routed instance pool keyed by instance address.
+func (p *Pool) InstanceStats() map[string]*pgxpool.Stat {
+	return p.provider.stats()
+}
+
+func (s *Stat) add(stat *pgxpool.Stat) {
+	s.instances++
+	s.acquiredConns += stat.AcquiredConns()
+	s.constructingConns += stat.ConstructingConns()
+	s.idleConns += stat.IdleConns()
+	s.maxConns += stat.MaxConns()
+	s.totalConns += stat.TotalConns()
+	s.addCumulative(stat)
+}
+
+// addCumulative adds only the cumulative counters of stat, e.g. of a removed instance pool.
+func (s *Stat) addCumulative(stat *pgxpool.Stat) {
+	s.acquireCount += stat.AcquireCount()
+	s.acquireDuration += stat.AcquireDuration()
+	s.canceledAcquireCount += stat.CanceledAcquireCount()
+	s.emptyAcquireCount += stat.EmptyAcquireCount()
+	s.newConnsCount += stat.NewConnsCount()
+	s.maxLifetimeDestroyCount += stat.MaxLifetimeDestroyCount()
+	s.maxIdleDestroyCount += stat.MaxIdleDestroyCount()
+}
+
+// Instances returns the number of instances the statistics are aggregated from.
+func (s *Stat) Instances() int {
+	return s.instances
+}
+
+// AcquireCount returns the cumulative count of successful acquires from the pools.
+func (s *Stat) AcquireCount() int64 {
+	return s.acquireCount
+}
+
+// AcquireDuration returns the total duration of all successful acquires from the pools.
+func (s *Stat) AcquireDuration() time.Duration {
+	return s.acquireDuration
+}
+
+// AcquiredConns returns the number of currently acquired connections in the pools.
+func (s *Stat) AcquiredConns() int32 {
+	return s.acquiredConns
+}
+
+// CanceledAcquireCount returns the cumulative count of acquires from the pools
+// that were canceled by a context.
+func (s *Stat) CanceledAcquireCount() int64 {
+	return s.canceledAcquireCount
+}
+
+// ConstructingConns returns the number of conns with construction in progress in the pools.
+func (s *Stat) ConstructingConns() int32 {
+	return s.constructingConns
+}
+
+// EmptyAcquireCount returns the cumulative count of successful acquires from the pools
+// that waited for a resource to be released or constructed because the pool was empty.
+func (s *Stat) EmptyAcquireCount() int64 {
+	return s.emptyAcquireCount
+}
+
+// IdleConns returns the number of currently idle conns in the pools.
+func (s *Stat) IdleConns() int32 {
+	return s.idleConns
+}
+
+// MaxConns returns the sum of the maximum sizes of the pools.
+func (s *Stat) MaxConns() int32 {
+	return s.maxConns
+}
+
+// TotalConns returns the total number of resources currently in the pools.
+// The value is the sum of ConstructingConns, AcquiredConns, and IdleConns.
+func (s *Stat) TotalConns() int32 {
+	return s.totalConns
+}
+
+// NewConnsCount returns the cumulative count of new connections opened.
+func (s *Stat) NewConnsCount() int64 {
+	return s.newConnsCount
+}
+
+// MaxLifetimeDestroyCount returns the cumulative count of connections destroyed
+// because they exceeded MaxConnLifetime.
+func (s *Stat) MaxLifetimeDestroyCount() int64 {
+	return s.maxLifetimeDestroyCount
+}
+
+// MaxIdleDestroyCount returns the cumulative count of connections destroyed because
+// they exceeded MaxConnIdleTime.
+func (s *Stat) MaxIdleDestroyCount() int64 {
+	return s.maxIdleDestroyCount
+}
@@ -0,0 +1,52 @@
+package picodata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolStat(t *testing.T) {
+	prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 3)
+	require.NoError(t, prov.addConn("127.0.0.1:5433"))
+	require.NoError(t, prov.addConn("127.0.0.1:5434"))
+	pool := &Pool{provider: prov}
+
+	stats := pool.InstanceStats()
+	require.Len(t, stats, 3)
+	assert.Contains(t, stats, "127.0.0.1:5432")
+	assert.Contains(t, stats, "127.0.0.1:5433")
+	assert.Contains(t, stats, "127.0.0.1:5434")
+
+	var maxConns int32
+	for _, s := range stats {
+		maxConns += s.MaxConns()
+	}
+
+	stat := pool.Stat()
+	assert.Equal(t, 3, stat.Instances())
+	assert.Equal(t, maxConns, stat.MaxConns())
+	assert.Equal(t, int32(0), stat.AcquiredConns())
+	assert.Equal(t, int64(0), stat.AcquireCount())
+
+	// A canceled acquire is counted without connecting to the instance
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := prov.connsMap()["127.0.0.1:5434"].Acquire(ctx)
+	require.Error(t, err)
+	assert.Equal(t, int64(1), pool.Stat().CanceledAcquireCount())
+
+	// Removed instances are not included, but their cumulative counters are kept
+	prov.removeConn("127.0.0.1:5434")
+	assert.Len(t, pool.InstanceStats(), 2)
+	stat = pool.Stat()
+	assert.Equal(t, 2, stat.Instances())
+	assert.Equal(t, maxConns-stats["127.0.0.1:5434"].MaxConns(), stat.MaxConns())
+	assert.Equal(t, int64(1), stat.CanceledAcquireCount())
+
+	require.Eventually(t, func() bool { return prov.drainingCount() == 0 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(1), pool.Stat().CanceledAcquireCount())
+}
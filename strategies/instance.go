@@ -0,0 +1,74 @@
+package strategies
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	_ InstanceStrategy = (*balanceStrategyAdapter)(nil)
+	_ LatencyObserver  = (*balanceStrategyAdapter)(nil)
+)
+
+// Metadata describes a Picodata instance as reported by the cluster.
+type Metadata struct {
+	RaftID         uint64
+	Name           string
+	ReplicasetName string
+	Tier           string
+	// FailureDomain is the instance failure domain, e.g. {"REGION": "EU", "ZONE": "EU-1"}.
+	// It is shared between calls and must not be modified.
+	FailureDomain map[string]string
+	// Leader reports whether the instance is the current master of its replicaset.
+	Leader bool
+}
+
+// Instance is a read-only view of an instance an operation may be routed to.
+// It is valid only during the Select call it is passed to. Instances that can't receive traffic,
+// e.g. offline ones or ones with an open circuit breaker, are never passed to strategies.
+type Instance interface {
+	// Address returns the instance address in host:port form.
+	Address() string
+	// Metadata returns the instance metadata. It is empty until the instance is reported by the cluster.
+	Metadata() Metadata
+	// Stat returns statistics of the instance connection pool.
+	Stat() *pgxpool.Stat
+	// AcquiredConns returns the number of connections to the instance currently in use.
+	AcquiredConns() int32
+}
+
+// InstanceStrategy chooses an instance to route an operation to, seeing metadata and load of every candidate.
+type InstanceStrategy interface {
+	// Select returns the index of the instance in candidates to route the operation executed with ctx to.
+	// candidates is never empty and must not be modified or retained.
+	Select(ctx context.Context, candidates []Instance) int
+	// Type returns the strategy type for identification.
+	Type() string
+}
+
+// FromBalanceStrategy adapts s to InstanceStrategy, s is given the number of candidates.
+// Latency is reported to s if it is a LatencyObserver.
+func FromBalanceStrategy(s BalanceStrategy) InstanceStrategy {
+	return &balanceStrategyAdapter{strategy: s}
+}
+
+type balanceStrategyAdapter struct {
+	strategy BalanceStrategy
+	current  uint64
+}
+
+func (a *balanceStrategyAdapter) Select(_ context.Context, candidates []Instance) int {
+	return int(a.strategy.Next(&a.current, uint64(len(candidates))))
+}
+
+func (a *balanceStrategyAdapter) Observe(address string, latency time.Duration, err error) {
+	if observer, ok := a.strategy.(LatencyObserver); ok {
+		observer.Observe(address, latency, err)
+	}
+}
+
+func (a *balanceStrategyAdapter) Type() string {
+	return a.strategy.Type()
+}
@@ -0,0 +1,71 @@
+package strategies_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/picodata/picodata-go/strategies"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeInstance struct {
+	address  string
+	meta     strategies.Metadata
+	acquired int32
+}
+
+func (i fakeInstance) Address() string               { return i.address }
+func (i fakeInstance) Metadata() strategies.Metadata { return i.meta }
+func (i fakeInstance) Stat() *pgxpool.Stat           { return nil }
+func (i fakeInstance) AcquiredConns() int32          { return i.acquired }
+
+// observingStrategy is a BalanceStrategy recording addresses latency is observed for
+type observingStrategy struct {
+	strategies.BalanceStrategy
+	observed []string
+}
+
+func (s *observingStrategy) Observe(address string, _ time.Duration, _ error) {
+	s.observed = append(s.observed, address)
+}
+
+func newFakeInstances(addresses ...string) []strategies.Instance {
+	instances := make([]strategies.Instance, len(addresses))
+	for i, address := range addresses {
+		instances[i] = fakeInstance{address: address}
+	}
+	return instances
+}
+
+func TestFromBalanceStrategy(t *testing.T) {
+	instances := newFakeInstances("127.0.0.1:5432", "127.0.0.1:5433", "127.0.0.1:5434")
+
+	t.Run("TestIndexStrategy", func(t *testing.T) {
+		strategy := strategies.FromBalanceStrategy(strategies.NewRoundRobinStrategy())
+		assert.Equal(t, "RoundRobin", strategy.Type())
+
+		for _, want := range []int{0, 1, 2, 0} {
+			assert.Equal(t, want, strategy.Select(context.Background(), instances))
+		}
+	})
+
+	t.Run("TestObserver", func(t *testing.T) {
+		observing := &observingStrategy{BalanceStrategy: strategies.NewRoundRobinStrategy()}
+		strategy := strategies.FromBalanceStrategy(observing)
+
+		// Latency is forwarded to the wrapped strategy
+		observer, ok := strategy.(strategies.LatencyObserver)
+		assert.True(t, ok)
+		observer.Observe("127.0.0.1:5432", time.Second, nil)
+		assert.Equal(t, []string{"127.0.0.1:5432"}, observing.observed)
+	})
+
+	t.Run("TestNotObserver", func(t *testing.T) {
+		strategy := strategies.FromBalanceStrategy(strategies.NewRandomStrategy())
+		assert.NotPanics(t, func() {
+			strategy.(strategies.LatencyObserver).Observe("127.0.0.1:5432", time.Second, nil)
+		})
+	})
+}
@@ -0,0 +1,38 @@
+package strategies
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+var _ InstanceStrategy = (*leastConnectionsStrategy)(nil)
+
+type leastConnectionsStrategy struct {
+	// offset rotates the scan start, so instances with equal load take turns
+	offset atomic.Uint64
+}
+
+// NewLeastConnectionsStrategy creates a strategy routing operations to the instance
+// with the fewest acquired connections. Instances with equal load are chosen in turn.
+func NewLeastConnectionsStrategy() *leastConnectionsStrategy {
+	return &leastConnectionsStrategy{}
+}
+
+func (s *leastConnectionsStrategy) Select(_ context.Context, candidates []Instance) int {
+	size := len(candidates)
+	start := int(s.offset.Add(1) % uint64(size))
+
+	best, bestConns := start, candidates[start].AcquiredConns()
+	for i := 1; i < size && bestConns > 0; i++ {
+		index := (start + i) % size
+		if conns := candidates[index].AcquiredConns(); conns < bestConns {
+			best, bestConns = index, conns
+		}
+	}
+
+	return best
+}
+
+func (s *leastConnectionsStrategy) Type() string {
+	return "LeastConnections"
+}
@@ -0,0 +1,63 @@
+package strategies_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/picodata/picodata-go/strategies"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLoadedInstances(acquired ...int32) []strategies.Instance {
+	instances := make([]strategies.Instance, len(acquired))
+	for i, conns := range acquired {
+		instances[i] = fakeInstance{acquired: conns}
+	}
+	return instances
+}
+
+func TestLeastConnectionsStrategy(t *testing.T) {
+	t.Run("TestType", func(t *testing.T) {
+		strategy := strategies.NewLeastConnectionsStrategy()
+		assert.Equal(t, strategy.Type(), "LeastConnections")
+	})
+
+	t.Run("TestSelect", func(t *testing.T) {
+		strategy := strategies.NewLeastConnectionsStrategy()
+		instances := newLoadedInstances(5, 2, 7, 3)
+
+		for range 10 {
+			assert.Equal(t, 1, strategy.Select(context.Background(), instances))
+		}
+	})
+
+	t.Run("TestEqualLoad", func(t *testing.T) {
+		strategy := strategies.NewLeastConnectionsStrategy()
+		instances := newLoadedInstances(1, 4, 1, 1)
+
+		counts := make(map[int]int)
+		for range 30 {
+			counts[strategy.Select(context.Background(), instances)]++
+		}
+		// Every instance with the lowest load takes turns, the loaded one is skipped
+		assert.Zero(t, counts[1])
+		for _, index := range []int{0, 2, 3} {
+			assert.Positive(t, counts[index], index)
+		}
+	})
+
+	t.Run("TestSingle", func(t *testing.T) {
+		strategy := strategies.NewLeastConnectionsStrategy()
+		assert.Equal(t, 0, strategy.Select(context.Background(), newLoadedInstances(3)))
+	})
+}
+
+func BenchmarkLeastConnectionsStrategy(b *testing.B) {
+	strategy := strategies.NewLeastConnectionsStrategy()
+	instances := newLoadedInstances(5, 2, 7, 3, 4, 6, 1, 8)
+
+	b.ResetTimer()
+	for range b.N {
+		strategy.Select(context.Background(), instances)
+	}
+}
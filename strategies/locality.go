@@ -0,0 +1,80 @@
+package strategies
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+var (
+	_ InstanceStrategy = (*localityStrategy)(nil)
+	_ LatencyObserver  = (*localityStrategy)(nil)
+)
+
+// localityStrategy routes operations to instances in the local failure domain.
+type localityStrategy struct {
+	// local has upper-cased keys and values, like failure domains stored by Picodata
+	local map[string]string
+	inner InstanceStrategy
+}
+
+// NewLocalityStrategy creates a strategy preferring instances in the local failure domain of the client,
+// e.g. {"REGION": "EU", "ZONE": "EU-1"}. An instance is local if its failure domain has every key of local
+// with the same value, compared case-insensitively. Operations are routed to remote instances only
+// when none of the local ones is available.
+//
+// inner chooses among the local instances or, on failover, among the remote ones.
+// If inner is nil, round-robin is used. Latency is reported to inner if it is a LatencyObserver.
+func NewLocalityStrategy(local map[string]string, inner InstanceStrategy) *localityStrategy {
+	if inner == nil {
+		inner = FromBalanceStrategy(NewRoundRobinStrategy())
+	}
+
+	normalized := make(map[string]string, len(local))
+	for key, value := range local {
+		normalized[strings.ToUpper(key)] = strings.ToUpper(value)
+	}
+
+	return &localityStrategy{local: normalized, inner: inner}
+}
+
+func (s *localityStrategy) Select(ctx context.Context, candidates []Instance) int {
+	var local []int
+	for i, inst := range candidates {
+		if s.isLocal(inst.Metadata().FailureDomain) {
+			local = append(local, i)
+		}
+	}
+
+	// Either every instance is local or there are no local ones to prefer
+	if len(local) == 0 || len(local) == len(candidates) {
+		return s.inner.Select(ctx, candidates)
+	}
+
+	localCandidates := make([]Instance, len(local))
+	for i, index := range local {
+		localCandidates[i] = candidates[index]
+	}
+
+	return local[s.inner.Select(ctx, localCandidates)]
+}
+
+func (s *localityStrategy) isLocal(domain map[string]string) bool {
+	for key, value := range s.local {
+		if !strings.EqualFold(domain[key], value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *localityStrategy) Observe(address string, latency time.Duration, err error) {
+	if observer, ok := s.inner.(LatencyObserver); ok {
+		observer.Observe(address, latency, err)
+	}
+}
+
+func (s *localityStrategy) Type() string {
+	return "Locality(" + s.inner.Type() + ")"
+}
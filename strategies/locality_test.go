@@ -0,0 +1,76 @@
+package strategies_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/picodata/picodata-go/strategies"
+	"github.com/stretchr/testify/assert"
+)
+
+func newZonedInstances(zones ...string) []strategies.Instance {
+	instances := make([]strategies.Instance, len(zones))
+	for i, zone := range zones {
+		meta := strategies.Metadata{}
+		if zone != "" {
+			meta.FailureDomain = map[string]string{"REGION": "EU", "ZONE": zone}
+		}
+		instances[i] = fakeInstance{address: zone, meta: meta}
+	}
+	return instances
+}
+
+func TestLocalityStrategy(t *testing.T) {
+	local := map[string]string{"region": "eu", "zone": "eu-1"}
+
+	t.Run("TestType", func(t *testing.T) {
+		assert.Equal(t, "Locality(RoundRobin)", strategies.NewLocalityStrategy(local, nil).Type())
+		assert.Equal(t, "Locality(LeastConnections)",
+			strategies.NewLocalityStrategy(local, strategies.NewLeastConnectionsStrategy()).Type())
+	})
+
+	t.Run("TestPrefersLocal", func(t *testing.T) {
+		strategy := strategies.NewLocalityStrategy(local, nil)
+		instances := newZonedInstances("EU-2", "EU-1", "", "EU-1")
+
+		for _, want := range []int{1, 3, 1, 3} {
+			assert.Equal(t, want, strategy.Select(context.Background(), instances))
+		}
+	})
+
+	t.Run("TestFailover", func(t *testing.T) {
+		strategy := strategies.NewLocalityStrategy(local, nil)
+		// Local instances are unavailable, so they are not among the candidates
+		instances := newZonedInstances("EU-2", "", "EU-3")
+
+		for _, want := range []int{0, 1, 2, 0} {
+			assert.Equal(t, want, strategy.Select(context.Background(), instances))
+		}
+	})
+
+	t.Run("TestPartialMatch", func(t *testing.T) {
+		strategy := strategies.NewLocalityStrategy(map[string]string{"REGION": "EU"}, nil)
+		instances := append(newZonedInstances("EU-1", "EU-2"),
+			fakeInstance{meta: strategies.Metadata{FailureDomain: map[string]string{"REGION": "US"}}})
+
+		counts := make(map[int]int)
+		for range 10 {
+			counts[strategy.Select(context.Background(), instances)]++
+		}
+		assert.Equal(t, map[int]int{0: 5, 1: 5}, counts)
+	})
+
+	t.Run("TestObserve", func(t *testing.T) {
+		inner := strategies.NewPeakEWMAStrategy(time.Second)
+		strategy := strategies.NewLocalityStrategy(local, inner)
+		instances := []strategies.Instance{
+			fakeInstance{address: "slow", meta: strategies.Metadata{FailureDomain: map[string]string{"REGION": "EU", "ZONE": "EU-1"}}},
+			fakeInstance{address: "fast", meta: strategies.Metadata{FailureDomain: map[string]string{"REGION": "EU", "ZONE": "EU-1"}}},
+		}
+
+		strategy.Observe("slow", time.Second, nil)
+		strategy.Observe("fast", time.Millisecond, nil)
+		assert.Equal(t, 1, strategy.Select(context.Background(), instances))
+	})
+}
@@ -0,0 +1,138 @@
+package strategies
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+var (
+	_ InstanceStrategy = (*peakEWMAStrategy)(nil)
+	_ LatencyObserver  = (*peakEWMAStrategy)(nil)
+)
+
+const (
+	// DefaultEWMADecay is the decay time used by NewPeakEWMAStrategy if decay is not positive.
+	DefaultEWMADecay = 10 * time.Second
+	// ewmaFailurePenalty is the latency sample recorded for a failed operation
+	ewmaFailurePenalty = time.Second
+	// ewmaTolerance makes instances with latency close to the best one share the load
+	ewmaTolerance = 1.25
+)
+
+type latencyStat struct {
+	// ewma is the moving average of latency in nanoseconds
+	ewma    float64
+	updated time.Time
+}
+
+// peakEWMAStrategy routes operations to the instances with the lowest latency.
+type peakEWMAStrategy struct {
+	decay time.Duration
+
+	mu sync.Mutex
+	// Key: instance address
+	stats map[string]*latencyStat
+}
+
+// NewPeakEWMAStrategy creates a latency-aware strategy. It keeps a peak-sensitive exponentially
+// weighted moving average of latency per instance and routes operations to the fastest instances:
+// an instance is chosen randomly among the ones whose average is within 25% of the best one.
+//
+// A latency spike is taken into account immediately, while improvements are averaged over decay.
+// The average of an instance that receives no operations decays towards zero with the same decay,
+// so a slow or failed instance is eventually retried and its latency is measured again.
+// Instances that have no measurements yet are preferred.
+func NewPeakEWMAStrategy(decay time.Duration) *peakEWMAStrategy {
+	if decay <= 0 {
+		decay = DefaultEWMADecay
+	}
+
+	return &peakEWMAStrategy{
+		decay: decay,
+		stats: make(map[string]*latencyStat),
+	}
+}
+
+func (s *peakEWMAStrategy) Select(_ context.Context, candidates []Instance) int {
+	now := time.Now()
+
+	scores := make([]float64, len(candidates))
+	best := math.Inf(1)
+
+	s.mu.Lock()
+	for i, inst := range candidates {
+		scores[i] = s.score(inst.Address(), now)
+		best = min(best, scores[i])
+	}
+	s.mu.Unlock()
+
+	limit := best * ewmaTolerance
+	near := 0
+	for _, score := range scores {
+		if score <= limit {
+			near++
+		}
+	}
+
+	// Choose a random instance among the ones close to the best
+	n := rand.N(near)
+	for i, score := range scores {
+		if score > limit {
+			continue
+		}
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+
+	return 0
+}
+
+func (s *peakEWMAStrategy) Observe(address string, latency time.Duration, err error) {
+	sample := float64(latency)
+	if err != nil {
+		sample = max(sample, float64(ewmaFailurePenalty))
+	}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stat, ok := s.stats[address]
+	if !ok {
+		s.stats[address] = &latencyStat{ewma: sample, updated: now}
+		return
+	}
+
+	if sample > stat.ewma {
+		stat.ewma = sample
+	} else {
+		w := s.weight(now.Sub(stat.updated))
+		stat.ewma = stat.ewma*w + sample*(1-w)
+	}
+	stat.updated = now
+}
+
+func (s *peakEWMAStrategy) Type() string {
+	return "PeakEWMA"
+}
+
+// score returns the latency average of address decayed by the time since the last measurement.
+// Must be called with mu held.
+func (s *peakEWMAStrategy) score(address string, now time.Time) float64 {
+	stat, ok := s.stats[address]
+	if !ok {
+		return 0
+	}
+
+	return stat.ewma * s.weight(now.Sub(stat.updated))
+}
+
+// weight returns how much of the previous average is kept after elapsed time.
+func (s *peakEWMAStrategy) weight(elapsed time.Duration) float64 {
+	return math.Exp(-float64(elapsed) / float64(s.decay))
+}
@@ -0,0 +1,76 @@
+package strategies_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/picodata/picodata-go/strategies"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeakEWMAStrategy(t *testing.T) {
+	addresses := []string{"dc1:5432", "dc1:5433", "dc2:5432"}
+	instances := newFakeInstances(addresses...)
+	ctx := context.Background()
+
+	t.Run("TestType", func(t *testing.T) {
+		strategy := strategies.NewPeakEWMAStrategy(0)
+		assert.Equal(t, strategy.Type(), "PeakEWMA")
+	})
+
+	t.Run("TestUnknownPreferred", func(t *testing.T) {
+		strategy := strategies.NewPeakEWMAStrategy(time.Minute)
+		strategy.Observe(addresses[0], time.Millisecond, nil)
+		strategy.Observe(addresses[1], time.Millisecond, nil)
+
+		for range 10 {
+			assert.Equal(t, 2, strategy.Select(ctx, instances))
+		}
+	})
+
+	t.Run("TestFastestPreferred", func(t *testing.T) {
+		strategy := strategies.NewPeakEWMAStrategy(time.Minute)
+		strategy.Observe(addresses[0], time.Millisecond, nil)
+		strategy.Observe(addresses[1], 1100*time.Microsecond, nil)
+		strategy.Observe(addresses[2], 30*time.Millisecond, nil)
+
+		// Instances with close latency share the load, the remote one gets nothing
+		counts := make(map[int]int)
+		for range 200 {
+			counts[strategy.Select(ctx, instances)]++
+		}
+		assert.Positive(t, counts[0])
+		assert.Positive(t, counts[1])
+		assert.Zero(t, counts[2])
+	})
+
+	t.Run("TestPeak", func(t *testing.T) {
+		strategy := strategies.NewPeakEWMAStrategy(time.Minute)
+		strategy.Observe(addresses[0], time.Millisecond, nil)
+		strategy.Observe(addresses[1], 5*time.Millisecond, nil)
+		assert.Equal(t, 0, strategy.Select(ctx, instances[:2]))
+
+		// Spike is taken into account immediately
+		strategy.Observe(addresses[0], 50*time.Millisecond, nil)
+		assert.Equal(t, 1, strategy.Select(ctx, instances[:2]))
+
+		// Failure is penalized even if it was fast
+		strategy.Observe(addresses[1], time.Microsecond, errors.New("connection refused"))
+		assert.Equal(t, 0, strategy.Select(ctx, instances[:2]))
+	})
+
+	t.Run("TestDecay", func(t *testing.T) {
+		strategy := strategies.NewPeakEWMAStrategy(10 * time.Millisecond)
+		strategy.Observe(addresses[0], time.Millisecond, nil)
+		strategy.Observe(addresses[1], time.Second, nil)
+		assert.Equal(t, 0, strategy.Select(ctx, instances[:2]))
+
+		// The slow instance isn't measured, so its average decays and it is retried
+		assert.Eventually(t, func() bool {
+			strategy.Observe(addresses[0], time.Millisecond, nil)
+			return strategy.Select(ctx, instances[:2]) == 1
+		}, time.Second, 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,41 @@
+package strategies
+
+import (
+	"context"
+	"math/rand/v2"
+)
+
+var _ InstanceStrategy = (*powerOfTwoChoicesStrategy)(nil)
+
+type powerOfTwoChoicesStrategy struct{}
+
+// NewPowerOfTwoChoicesStrategy creates a strategy that samples two random instances
+// and routes operations to the one with fewer acquired connections.
+// It avoids overloading a slow instance like least connections does,
+// but checks the load of only two instances and doesn't make all clients rush to the same instance.
+func NewPowerOfTwoChoicesStrategy() powerOfTwoChoicesStrategy {
+	return powerOfTwoChoicesStrategy{}
+}
+
+func (s powerOfTwoChoicesStrategy) Select(_ context.Context, candidates []Instance) int {
+	size := len(candidates)
+	if size == 1 {
+		return 0
+	}
+
+	// Sample two distinct instances
+	first := rand.N(size)
+	second := rand.N(size - 1)
+	if second >= first {
+		second++
+	}
+
+	if candidates[second].AcquiredConns() < candidates[first].AcquiredConns() {
+		return second
+	}
+	return first
+}
+
+func (s powerOfTwoChoicesStrategy) Type() string {
+	return "PowerOfTwoChoices"
+}
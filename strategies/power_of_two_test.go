@@ -0,0 +1,65 @@
+package strategies_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/picodata/picodata-go/strategies"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPowerOfTwoChoicesStrategy(t *testing.T) {
+	t.Run("TestType", func(t *testing.T) {
+		strategy := strategies.NewPowerOfTwoChoicesStrategy()
+		assert.Equal(t, strategy.Type(), "PowerOfTwoChoices")
+	})
+
+	t.Run("TestSelect", func(t *testing.T) {
+		strategy := strategies.NewPowerOfTwoChoicesStrategy()
+		instances := newLoadedInstances(5, 2, 7, 0)
+
+		counts := make(map[int]int)
+		for range 1000 {
+			got := strategy.Select(context.Background(), instances)
+			assert.Less(t, got, len(instances))
+			counts[got]++
+		}
+
+		// The most loaded instance wins only against itself, which is never sampled twice
+		assert.Zero(t, counts[2])
+		// The least loaded one wins every time it is sampled
+		assert.Greater(t, counts[3], counts[0])
+		assert.Greater(t, counts[3], counts[1])
+	})
+
+	t.Run("TestSingle", func(t *testing.T) {
+		strategy := strategies.NewPowerOfTwoChoicesStrategy()
+		assert.Equal(t, 0, strategy.Select(context.Background(), newLoadedInstances(3)))
+	})
+
+	t.Run("TestSelectConcurrent", func(t *testing.T) {
+		strategy := strategies.NewPowerOfTwoChoicesStrategy()
+		instances := newLoadedInstances(1, 2, 3)
+
+		var wg sync.WaitGroup
+		for range 100 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Less(t, strategy.Select(context.Background(), instances), len(instances))
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func BenchmarkPowerOfTwoChoicesStrategy(b *testing.B) {
+	strategy := strategies.NewPowerOfTwoChoicesStrategy()
+	instances := newLoadedInstances(5, 2, 7, 3, 4, 6, 1, 8)
+
+	b.ResetTimer()
+	for range b.N {
+		strategy.Select(context.Background(), instances)
+	}
+}
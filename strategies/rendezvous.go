@@ -0,0 +1,93 @@
+package strategies
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+)
+
+var (
+	_ InstanceStrategy = (*rendezvousStrategy)(nil)
+	_ LatencyObserver  = (*rendezvousStrategy)(nil)
+)
+
+type routingKey struct{}
+
+// WithRoutingKey returns a copy of ctx carrying the key operations are routed by,
+// e.g. a tenant id. See NewRendezvousStrategy.
+func WithRoutingKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, routingKey{}, key)
+}
+
+// RoutingKey returns the key set with WithRoutingKey.
+func RoutingKey(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(routingKey{}).(string)
+	return key, ok
+}
+
+// rendezvousStrategy routes operations with the same routing key to the same instance.
+type rendezvousStrategy struct {
+	fallback InstanceStrategy
+}
+
+// NewRendezvousStrategy creates a strategy routing operations with the same routing key,
+// set with WithRoutingKey, to the same instance while the topology is stable. It uses rendezvous
+// (highest random weight) hashing: every instance is scored by the hash of the key and its address,
+// and the one with the highest score wins. When an instance is added, it takes over only the keys
+// it scores highest for; when it is removed or becomes unavailable, only its keys move to other instances.
+//
+// Operations without a routing key are routed by fallback. If fallback is nil, round-robin is used.
+// Latency is reported to fallback if it is a LatencyObserver.
+func NewRendezvousStrategy(fallback InstanceStrategy) *rendezvousStrategy {
+	if fallback == nil {
+		fallback = FromBalanceStrategy(NewRoundRobinStrategy())
+	}
+
+	return &rendezvousStrategy{fallback: fallback}
+}
+
+func (s *rendezvousStrategy) Select(ctx context.Context, candidates []Instance) int {
+	key, ok := RoutingKey(ctx)
+	if !ok {
+		return s.fallback.Select(ctx, candidates)
+	}
+
+	best, bestScore := 0, uint64(0)
+	for i, inst := range candidates {
+		// Ties are broken by the address, so the choice doesn't depend on the order of candidates
+		score := rendezvousScore(key, inst.Address())
+		if i == 0 || score > bestScore || (score == bestScore && inst.Address() > candidates[best].Address()) {
+			best, bestScore = i, score
+		}
+	}
+
+	return best
+}
+
+// rendezvousScore returns the weight of the instance with address for key.
+func rendezvousScore(key, address string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(address))
+
+	// FNV-1a scores of similar addresses are correlated, so they are mixed (splitmix64 finalizer)
+	score := h.Sum64()
+	score ^= score >> 30
+	score *= 0xbf58476d1ce4e5b9
+	score ^= score >> 27
+	score *= 0x94d049bb133111eb
+	score ^= score >> 31
+
+	return score
+}
+
+func (s *rendezvousStrategy) Observe(address string, latency time.Duration, err error) {
+	if observer, ok := s.fallback.(LatencyObserver); ok {
+		observer.Observe(address, latency, err)
+	}
+}
+
+func (s *rendezvousStrategy) Type() string {
+	return "Rendezvous"
+}
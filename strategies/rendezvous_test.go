@@ -0,0 +1,108 @@
+package strategies_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/picodata/picodata-go/strategies"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// selectAddress returns the address of the instance chosen for key
+func selectAddress(s strategies.InstanceStrategy, key string, instances []strategies.Instance) string {
+	ctx := strategies.WithRoutingKey(context.Background(), key)
+	return instances[s.Select(ctx, instances)].Address()
+}
+
+func TestRendezvousStrategy(t *testing.T) {
+	addresses := []string{"127.0.0.1:5432", "127.0.0.1:5433", "127.0.0.1:5434", "127.0.0.1:5435"}
+	instances := newFakeInstances(addresses...)
+
+	t.Run("TestType", func(t *testing.T) {
+		assert.Equal(t, "Rendezvous", strategies.NewRendezvousStrategy(nil).Type())
+	})
+
+	t.Run("TestRoutingKey", func(t *testing.T) {
+		_, ok := strategies.RoutingKey(context.Background())
+		assert.False(t, ok)
+
+		key, ok := strategies.RoutingKey(strategies.WithRoutingKey(context.Background(), "tenant"))
+		assert.True(t, ok)
+		assert.Equal(t, "tenant", key)
+	})
+
+	t.Run("TestSticky", func(t *testing.T) {
+		strategy := strategies.NewRendezvousStrategy(nil)
+
+		reversed := slices.Clone(instances)
+		slices.Reverse(reversed)
+
+		counts := make(map[string]int)
+		for i := range 1000 {
+			key := fmt.Sprintf("tenant-%d", i)
+			address := selectAddress(strategy, key, instances)
+			counts[address]++
+
+			// The same key is routed to the same instance regardless of the order of candidates
+			assert.Equal(t, address, selectAddress(strategy, key, instances))
+			assert.Equal(t, address, selectAddress(strategy, key, reversed))
+		}
+
+		// Keys are spread across all instances
+		require.Len(t, counts, len(addresses))
+		for address, count := range counts {
+			assert.Greater(t, count, 150, address)
+		}
+	})
+
+	t.Run("TestTopologyChange", func(t *testing.T) {
+		strategy := strategies.NewRendezvousStrategy(nil)
+		removed := addresses[1]
+		remaining := newFakeInstances(slices.Delete(slices.Clone(addresses), 1, 2)...)
+		added := newFakeInstances(append(slices.Clone(addresses), "127.0.0.1:5436")...)
+
+		moved := 0
+		for i := range 1000 {
+			key := fmt.Sprintf("tenant-%d", i)
+			before := selectAddress(strategy, key, instances)
+
+			// Only keys of the removed instance move
+			after := selectAddress(strategy, key, remaining)
+			if before != removed {
+				assert.Equal(t, before, after, key)
+			}
+
+			// A new instance takes keys over, other keys stay in place
+			if after := selectAddress(strategy, key, added); after != before {
+				assert.Equal(t, "127.0.0.1:5436", after, key)
+				moved++
+			}
+		}
+		assert.Greater(t, moved, 100)
+		assert.Less(t, moved, 300)
+	})
+
+	t.Run("TestFallback", func(t *testing.T) {
+		strategy := strategies.NewRendezvousStrategy(nil)
+		for _, want := range []int{0, 1, 2, 3, 0} {
+			assert.Equal(t, want, strategy.Select(context.Background(), instances))
+		}
+
+		leastConns := strategies.NewRendezvousStrategy(strategies.NewLeastConnectionsStrategy())
+		assert.Equal(t, 1, leastConns.Select(context.Background(), newLoadedInstances(3, 0, 2)))
+	})
+}
+
+func BenchmarkRendezvousStrategy(b *testing.B) {
+	strategy := strategies.NewRendezvousStrategy(nil)
+	instances := newFakeInstances("127.0.0.1:5432", "127.0.0.1:5433", "127.0.0.1:5434", "127.0.0.1:5435")
+	ctx := strategies.WithRoutingKey(context.Background(), "tenant")
+
+	b.ResetTimer()
+	for range b.N {
+		strategy.Select(ctx, instances)
+	}
+}
@@ -1,6 +1,7 @@
 package strategies_test
 
 import (
+	"context"
 	"sync"
 	"testing"
 
@@ -84,3 +85,13 @@ func TestRoundRobinStrategy(t *testing.T) {
 		assert.LessOrEqual(t, got, poolSize)
 	})
 }
+
+func BenchmarkRoundRobinStrategy(b *testing.B) {
+	strategy := strategies.FromBalanceStrategy(strategies.NewRoundRobinStrategy())
+	instances := newLoadedInstances(5, 2, 7, 3, 4, 6, 1, 8)
+
+	b.ResetTimer()
+	for range b.N {
+		strategy.Select(context.Background(), instances)
+	}
+}
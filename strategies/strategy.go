@@ -1,5 +1,7 @@
 package strategies
 
+import "time"
+
 // Balancer is the interface for load balancing strategies.
 type BalanceStrategy interface {
 	// Next returns the next index to use from the pool, given the current index pointer and pool size.
@@ -7,3 +9,11 @@ type BalanceStrategy interface {
 	// Type returns the strategy type for identification.
 	Type() string
 }
+
+// LatencyObserver is implemented by strategies that take operation latency into account.
+// The pool calls Observe after every operation attempt.
+type LatencyObserver interface {
+	// Observe reports the latency of an operation on the instance with address.
+	// err is not nil if the instance failed to serve the operation, e.g. the connection was refused.
+	Observe(address string, latency time.Duration, err error)
+}
@@ -0,0 +1,52 @@
+package picodata
+
+import "context"
+
+type tierKey struct{}
+
+// WithTier returns a copy of ctx routing operations executed with it to instances of tier only.
+// If none of them is available, operations wait for one as long as [WithInstanceWaitTimeout] allows
+// and fail with ErrNoAvailableInstances.
+func WithTier(ctx context.Context, tier string) context.Context {
+	return context.WithValue(ctx, tierKey{}, tier)
+}
+
+func tierFromContext(ctx context.Context) (string, bool) {
+	tier, ok := ctx.Value(tierKey{}).(string)
+	return tier, ok
+}
+
+// instancesOfTier returns instances of tier. The slice is always copied.
+func instancesOfTier(instances []*instance, tier string) []*instance {
+	candidates := make([]*instance, 0, len(instances))
+	for _, inst := range instances {
+		if inst.meta.tier == tier {
+			candidates = append(candidates, inst)
+		}
+	}
+
+	return candidates
+}
+
+type preferredTierKey struct{}
+
+// withPreferredTier returns a copy of ctx routing operations executed with it to instances of tier
+// while any of them is available.
+func withPreferredTier(ctx context.Context, tier string) context.Context {
+	return context.WithValue(ctx, preferredTierKey{}, tier)
+}
+
+func preferredTierFromContext(ctx context.Context) (string, bool) {
+	tier, ok := ctx.Value(preferredTierKey{}).(string)
+	return tier, ok
+}
+
+// instancesPreferringTier returns instances of tier or all instances if none of them is available,
+// e.g. if the pool routes to other tiers only, so the operation is forwarded by another instance.
+func instancesPreferringTier(instances []*instance, tier string) []*instance {
+	candidates := instancesOfTier(instances, tier)
+	if len(candidates) == 0 {
+		return instances
+	}
+	return candidates
+}
@@ -0,0 +1,111 @@
+package picodata
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Topology is a snapshot of the instances known to the pool.
+type Topology struct {
+	// Generation is incremented every time an instance is added to or removed from routing.
+	Generation uint64
+	// Instances are sorted by address.
+	Instances []InstanceInfo
+}
+
+// InstanceInfo describes a Picodata instance known to the pool.
+type InstanceInfo struct {
+	Address        string
+	RaftID         uint64
+	Name           string
+	ReplicasetName string
+	Tier           string
+	// FailureDomain is nil if the instance has no failure domain.
+	FailureDomain map[string]string
+	// Leader reports whether the instance is the current master of its replicaset.
+	Leader       bool
+	CurrentState InstanceState
+	TargetState  InstanceState
+	// StateChangedAt is the time the pool observed the last change of the instance state.
+	// It is zero if the instance hasn't been reported by the cluster yet.
+	StateChangedAt time.Time
+	// Routed reports whether the pool routes operations to the instance.
+	Routed bool
+	// Stat is the instance pool statistics at the time of the snapshot, nil if the instance isn't routed.
+	Stat *pgxpool.Stat
+	// CircuitState is always CircuitClosed if circuit breaking is disabled.
+	CircuitState CircuitState
+}
+
+// Topology returns a snapshot of all instances known to the pool:
+// the ones it routes operations to and the ones the cluster reports as unavailable.
+func (p *Pool) Topology() Topology {
+	return p.provider.topology()
+}
+
+// Instances returns instances known to the pool. It is a shortcut for Topology().Instances.
+func (p *Pool) Instances() []InstanceInfo {
+	return p.provider.topology().Instances
+}
+
+// TopologyEventType is the kind of a topology change, see [TopologyEvent].
+type TopologyEventType int
+
+const (
+	// TopologyEventInstanceAdded means operations started being routed to the instance.
+	TopologyEventInstanceAdded TopologyEventType = iota
+	// TopologyEventInstanceRemoved means operations are no longer routed to the instance.
+	TopologyEventInstanceRemoved
+	// TopologyEventStateChanged means the cluster reported a new current or target state of the instance.
+	TopologyEventStateChanged
+)
+
+func (t TopologyEventType) String() string {
+	switch t {
+	case TopologyEventInstanceAdded:
+		return "instance added"
+	case TopologyEventInstanceRemoved:
+		return "instance removed"
+	case TopologyEventStateChanged:
+		return "state changed"
+	default:
+		return "unknown"
+	}
+}
+
+// TopologyEvent describes a change of the cluster topology observed by the pool.
+type TopologyEvent struct {
+	Type           TopologyEventType
+	Address        string
+	RaftID         uint64
+	Name           string
+	ReplicasetName string
+	Tier           string
+	FailureDomain  map[string]string
+	Leader         bool
+	CurrentState   InstanceState
+	TargetState    InstanceState
+	// Time is when the pool processed the change.
+	Time time.Time
+}
+
+// TopologyHooks are called on topology changes, see [WithTopologyHooks].
+// Hooks are called synchronously by the topology manager, so they must not block.
+// Any of them may be nil.
+type TopologyHooks struct {
+	OnInstanceAdded   func(TopologyEvent)
+	OnInstanceRemoved func(TopologyEvent)
+	OnStateChanged    func(TopologyEvent)
+}
+
+// SubscribeTopology returns a channel receiving topology changes until ctx is done or the pool is closed,
+// after which the channel is closed.
+//
+// Events are delivered without blocking: if the subscriber doesn't keep up and the channel buffer is full,
+// events are dropped. Use [Pool.Topology] to get the current state after a drop.
+// No events are delivered if topology managing is disabled.
+func (p *Pool) SubscribeTopology(ctx context.Context) <-chan TopologyEvent {
+	return p.notifier.subscribe(ctx)
+}
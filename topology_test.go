@@ -0,0 +1,182 @@
+package picodata
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/picodata/picodata-go/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopology(t *testing.T) {
+	prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+	pool := &Pool{provider: prov}
+
+	// Initial instance is routed before it is reported by the cluster
+	topology := pool.Topology()
+	require.Len(t, topology.Instances, 1)
+	assert.Equal(t, "127.0.0.1:5432", topology.Instances[0].Address)
+	assert.True(t, topology.Instances[0].Routed)
+	assert.NotNil(t, topology.Instances[0].Stat)
+	assert.True(t, topology.Instances[0].StateChangedAt.IsZero())
+
+	online := connState{
+		address:      "127.0.0.1:5433",
+		currentState: InstanceStateOnline,
+		targetState:  InstanceStateOnline,
+		meta:         instanceMeta{raftID: 2, name: "default_1_2", replicasetName: "default_1", tier: "default"},
+	}
+	offline := connState{
+		address:      "127.0.0.1:5434",
+		currentState: InstanceStateOffline,
+		targetState:  InstanceStateOffline,
+		meta:         instanceMeta{raftID: 3, name: "default_2_1", replicasetName: "default_2", tier: "default"},
+	}
+	prov.updateState(online)
+	prov.updateState(offline)
+	require.NoError(t, prov.addConn(online.address))
+
+	topology = pool.Topology()
+	assert.Equal(t, uint64(1), topology.Generation)
+	require.Len(t, topology.Instances, 3)
+
+	// Instances are sorted by address
+	inst := topology.Instances[1]
+	assert.Equal(t, online.address, inst.Address)
+	assert.Equal(t, uint64(2), inst.RaftID)
+	assert.Equal(t, "default_1_2", inst.Name)
+	assert.Equal(t, "default_1", inst.ReplicasetName)
+	assert.Equal(t, "default", inst.Tier)
+	assert.Equal(t, InstanceStateOnline, inst.CurrentState)
+	assert.Equal(t, InstanceStateOnline, inst.TargetState)
+	assert.False(t, inst.StateChangedAt.IsZero())
+	assert.True(t, inst.Routed)
+	assert.NotNil(t, inst.Stat)
+	assert.Equal(t, CircuitClosed, inst.CircuitState)
+
+	inst = topology.Instances[2]
+	assert.Equal(t, offline.address, inst.Address)
+	assert.Equal(t, InstanceStateOffline, inst.CurrentState)
+	assert.False(t, inst.Routed)
+	assert.Nil(t, inst.Stat)
+
+	// Repeated state doesn't move the change time
+	changedAt := topology.Instances[1].StateChangedAt
+	prov.updateState(online)
+	assert.Equal(t, changedAt, pool.Instances()[1].StateChangedAt)
+
+	online.targetState = InstanceStateOffline
+	prov.updateState(online)
+	prov.removeConn(online.address)
+
+	topology = pool.Topology()
+	assert.Equal(t, uint64(2), topology.Generation)
+	inst = topology.Instances[1]
+	assert.Equal(t, InstanceStateOffline, inst.TargetState)
+	assert.False(t, inst.Routed)
+	assert.False(t, inst.StateChangedAt.Before(changedAt))
+}
+
+func TestTopologySubscription(t *testing.T) {
+	t.Run("TestHooksAndSubscribers", func(t *testing.T) {
+		var added, removed, changed []string
+		notifier := newTopologyNotifier(TopologyHooks{
+			OnInstanceAdded:   func(e TopologyEvent) { added = append(added, e.Address) },
+			OnInstanceRemoved: func(e TopologyEvent) { removed = append(removed, e.Address) },
+			OnStateChanged:    func(e TopologyEvent) { changed = append(changed, e.Address) },
+		}, logger.Default())
+		pool := &Pool{notifier: notifier}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sub := pool.SubscribeTopology(ctx)
+
+		prov := newConnectionProvider(newMockPool("127.0.0.1", 5432), 1)
+		manager := newTopologyManager(prov, notifier)
+
+		eventChan := make(chan event, 10)
+		eventChan <- event{address: "127.0.0.1:5433", state: InstanceStateOnline, targetState: InstanceStateOnline}
+		// Same state must not be reported again
+		eventChan <- event{address: "127.0.0.1:5433", state: InstanceStateOnline, targetState: InstanceStateOnline}
+		eventChan <- event{address: "127.0.0.1:5434", state: InstanceStateOffline, targetState: InstanceStateOffline}
+		eventChan <- event{address: "127.0.0.1:5433", state: InstanceStateOffline, targetState: InstanceStateOffline}
+		close(eventChan)
+		manager.runProcessing(eventChan)
+
+		assert.Equal(t, []string{"127.0.0.1:5433"}, added)
+		assert.Equal(t, []string{"127.0.0.1:5433"}, removed)
+		assert.Equal(t, []string{"127.0.0.1:5433", "127.0.0.1:5434", "127.0.0.1:5433"}, changed)
+
+		var types []TopologyEventType
+		for range 5 {
+			e := <-sub
+			types = append(types, e.Type)
+			assert.False(t, e.Time.IsZero())
+		}
+		assert.Equal(t, []TopologyEventType{
+			TopologyEventStateChanged,
+			TopologyEventInstanceAdded,
+			TopologyEventStateChanged,
+			TopologyEventStateChanged,
+			TopologyEventInstanceRemoved,
+		}, types)
+
+		cancel()
+		require.Eventually(t, func() bool {
+			_, ok := <-sub
+			return !ok
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("TestSlowSubscriber", func(t *testing.T) {
+		notifier := newTopologyNotifier(TopologyHooks{}, logger.Default())
+		sub := notifier.subscribe(context.Background())
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for range defaultSubscriberBufferSize * 2 {
+				notifier.publish(TopologyEvent{Type: TopologyEventStateChanged})
+			}
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("publish is blocked by a slow subscriber")
+		}
+		assert.Len(t, sub, defaultSubscriberBufferSize)
+
+		notifier.close()
+		for range sub {
+		}
+		_, ok := <-sub
+		assert.False(t, ok)
+
+		// Subscribing to a closed notifier returns a closed channel
+		_, ok = <-notifier.subscribe(context.Background())
+		assert.False(t, ok)
+	})
+
+	t.Run("TestCloseStopsSubscriptions", func(t *testing.T) {
+		notifier := newTopologyNotifier(TopologyHooks{}, logger.Default())
+		before := runtime.NumGoroutine()
+
+		// Subscriptions whose context is never canceled must not outlive the notifier
+		for range 10 {
+			notifier.subscribe(context.Background())
+		}
+		notifier.close()
+		notifier.close()
+
+		// NOTE: Eventually runs the condition in a goroutine of its own, so it would be counted
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+	})
+}
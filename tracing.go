@@ -0,0 +1,77 @@
+package picodata
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Span attributes set by the pool, see [WithTracer].
+const (
+	attrDBSystem           = attribute.Key("db.system")
+	attrInstanceAddress    = attribute.Key("picodata.instance.address")
+	attrBalanceStrategy    = attribute.Key("picodata.balance_strategy")
+	attrRetryAttempt       = attribute.Key("picodata.retry.attempt")
+	attrTopologyGeneration = attribute.Key("picodata.topology.generation")
+	attrTopologyInstances  = attribute.Key("picodata.topology.instances")
+)
+
+const (
+	spanQuery        = "picodata.Query"
+	spanExec         = "picodata.Exec"
+	spanCopyFrom     = "picodata.CopyFrom"
+	spanSendBatch    = "picodata.SendBatch"
+	spanBegin        = "picodata.Begin"
+	spanAcquire      = "picodata.Acquire"
+	spanTopologyPoll = "picodata.topology.poll"
+)
+
+var dbSystemPicodata = attrDBSystem.String("picodata")
+
+// noopTracer is used when tracing is not configured, its spans are not recorded.
+var noopTracer trace.Tracer = noop.NewTracerProvider().Tracer("")
+
+func tracerOrNoop(tracer trace.Tracer) trace.Tracer {
+	if tracer == nil {
+		return noopTracer
+	}
+	return tracer
+}
+
+// startSpan starts a client span of a pool operation.
+func (p *Pool) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	ctx, span := tracerOrNoop(p.tracer).Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	if span.IsRecording() {
+		span.SetAttributes(
+			dbSystemPicodata,
+			attrBalanceStrategy.String(p.provider.strategyType()),
+		)
+	}
+
+	return ctx, span
+}
+
+// traceAttempt records the instance the operation attempt is routed to.
+func (p *Pool) traceAttempt(span trace.Span, inst *instance, attempt int) {
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetAttributes(
+		attrInstanceAddress.String(inst.address),
+		attrRetryAttempt.Int(attempt),
+		attrTopologyGeneration.Int64(int64(p.provider.currentGeneration())),
+	)
+}
+
+// endSpan records err, if any, and ends span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,190 @@
+package picodata
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanCapturingTracer is a user-defined pgx tracer remembering the spans it was called within
+type spanCapturingTracer struct {
+	mu    sync.Mutex
+	spans []trace.SpanID
+}
+
+func (t *spanCapturingTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return ctx
+}
+
+func (t *spanCapturingTracer) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
+
+func (t *spanCapturingTracer) TraceAcquireStart(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	t.mu.Lock()
+	t.spans = append(t.spans, trace.SpanFromContext(ctx).SpanContext().SpanID())
+	t.mu.Unlock()
+	return ctx
+}
+
+func (t *spanCapturingTracer) TraceAcquireEnd(context.Context, *pgxpool.Pool, pgxpool.TraceAcquireEndData) {
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	// Nothing listens on ports 1 and 2, so every attempt fails with connection refused
+	queryTracer := &spanCapturingTracer{}
+	cfg, err := pgxpool.ParseConfig("host=127.0.0.1 port=1")
+	require.NoError(t, err)
+	cfg.ConnConfig.Tracer = queryTracer
+	initConn, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	require.NoError(t, err)
+
+	prov := newConnectionProvider(initConn, 1)
+	require.NoError(t, prov.addConn("127.0.0.1:2"))
+	defer prov.close()
+	pool := &Pool{provider: prov, retryPolicy: &recordingRetryPolicy{maxAttempts: 3}, tracer: tracer}
+
+	_, err = pool.Exec(context.Background(), "SELECT 1")
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+
+	assert.Equal(t, spanExec, span.Name())
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+	assert.Equal(t, codes.Error, span.Status().Code)
+
+	attrs := attribute.NewSet(span.Attributes()...)
+	for key, want := range map[attribute.Key]attribute.Value{
+		attrDBSystem:           attribute.StringValue("picodata"),
+		attrBalanceStrategy:    attribute.StringValue("RoundRobin"),
+		attrInstanceAddress:    attribute.StringValue("127.0.0.1:1"),
+		attrRetryAttempt:       attribute.IntValue(3),
+		attrTopologyGeneration: attribute.Int64Value(1),
+	} {
+		got, ok := attrs.Value(key)
+		if assert.True(t, ok, key) {
+			assert.Equal(t, want, got, key)
+		}
+	}
+
+	var retries int
+	for _, e := range span.Events() {
+		if e.Name == "retry" {
+			retries++
+		}
+	}
+	assert.Equal(t, 2, retries)
+
+	// The user tracer is copied to every instance and called within the pool span
+	require.Len(t, queryTracer.spans, 3)
+	for _, id := range queryTracer.spans {
+		assert.Equal(t, span.SpanContext().SpanID(), id)
+	}
+}
+
+func TestTracingDisabled(t *testing.T) {
+	pool := &Pool{provider: newConnectionProvider(newMockPool("127.0.0.1", 1), 1)}
+
+	_, span := pool.startSpan(context.Background(), spanQuery)
+	assert.False(t, span.IsRecording())
+}
+
+func TestTopologyPollTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	prov := newConnectionProvider(newMockPool("127.0.0.1", 1), 1)
+	defer prov.close()
+
+	config := defaultProducerConfig()
+	config.tracer = tracer
+	producer, err := newStateProducer(prov, "", config)
+	require.NoError(t, err)
+
+	_, err = producer.getConnStates()
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, spanTopologyPoll, spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+
+	attrs := attribute.NewSet(spans[0].Attributes()...)
+	address, ok := attrs.Value(attrInstanceAddress)
+	assert.True(t, ok)
+	assert.Equal(t, "127.0.0.1:1", address.AsString())
+}
+
+// committingTx is a pgx.Tx whose Commit and Rollback return err
+type committingTx struct {
+	pgx.Tx
+	err error
+}
+
+func (tx committingTx) Commit(context.Context) error   { return tx.err }
+func (tx committingTx) Rollback(context.Context) error { return tx.err }
+
+func TestSpanEndsWithResult(t *testing.T) {
+	newTracer := func() (*tracetest.SpanRecorder, trace.Tracer) {
+		recorder := tracetest.NewSpanRecorder()
+		return recorder, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	}
+	readErr := errors.New("read failed")
+
+	t.Run("TestRows", func(t *testing.T) {
+		recorder, tracer := newTracer()
+		_, span := tracer.Start(context.Background(), spanQuery)
+		rows := newTracedRows(errRows{err: readErr}, span)
+		assert.Empty(t, recorder.Ended(), "span must last until the rows are read")
+
+		assert.False(t, rows.Next())
+		rows.Close()
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Equal(t, readErr.Error(), spans[0].Status().Description)
+	})
+
+	t.Run("TestTx", func(t *testing.T) {
+		recorder, tracer := newTracer()
+		_, span := tracer.Start(context.Background(), spanBegin)
+		tx := &Tx{Tx: committingTx{err: readErr}, span: span}
+		assert.Empty(t, recorder.Ended(), "span must last until the transaction is committed")
+
+		assert.ErrorIs(t, tx.Commit(context.Background()), readErr)
+		assert.ErrorIs(t, tx.Rollback(context.Background()), readErr)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	})
+
+	t.Run("TestBatch", func(t *testing.T) {
+		recorder, tracer := newTracer()
+		_, span := tracer.Start(context.Background(), spanSendBatch)
+		results := &poolBatchResults{br: errBatchResults{err: readErr}, span: span}
+		assert.Empty(t, recorder.Ended(), "span must last until the batch results are closed")
+
+		assert.ErrorIs(t, results.Close(), readErr)
+		assert.ErrorIs(t, results.Close(), readErr)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	})
+}
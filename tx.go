@@ -0,0 +1,155 @@
+package picodata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrSavepointNotSupported is returned when a nested transaction is started.
+// Picodata SQL layer doesn't support savepoints, which pgx uses for nested transactions.
+var ErrSavepointNotSupported = errors.New("picodata: savepoints (nested transactions) are not supported")
+
+// TxOptionNotSupportedError is returned by BeginTx when txOptions contain
+// a transaction characteristic Picodata SQL layer doesn't support.
+type TxOptionNotSupportedError struct {
+	Option string
+	Value  string
+}
+
+func (e *TxOptionNotSupportedError) Error() string {
+	return fmt.Sprintf("picodata: transaction option %s %q is not supported", e.Option, e.Value)
+}
+
+// Tx is a transaction pinned to a single Picodata instance.
+//
+// Picodata accepts BEGIN, COMMIT and ROLLBACK, but doesn't support interactive transactions:
+// every statement is applied as soon as it is executed. Tx gives no atomicity, Rollback doesn't undo
+// statements executed in the transaction. It only makes them run on the same instance in order.
+type Tx struct {
+	pgx.Tx
+	address string
+	// span of Begin is ended on commit or rollback, it's nil if the transaction has been started on Conn
+	span trace.Span
+}
+
+// Address returns the address of the Picodata instance the transaction runs on.
+func (tx *Tx) Address() string {
+	return tx.address
+}
+
+// Commit commits the transaction. The span of Begin ends then, recording the error, if any.
+func (tx *Tx) Commit(ctx context.Context) error {
+	err := tx.Tx.Commit(ctx)
+	tx.endSpan(err)
+	return err
+}
+
+// Rollback rolls back the transaction. The span of Begin ends then, recording the error, if any.
+func (tx *Tx) Rollback(ctx context.Context) error {
+	err := tx.Tx.Rollback(ctx)
+	tx.endSpan(err)
+	return err
+}
+
+func (tx *Tx) endSpan(err error) {
+	if tx.span == nil {
+		return
+	}
+	endSpan(tx.span, err)
+	tx.span = nil
+}
+
+// Begin always returns ErrSavepointNotSupported, because Picodata doesn't support savepoints.
+func (tx *Tx) Begin(ctx context.Context) (pgx.Tx, error) {
+	return nil, ErrSavepointNotSupported
+}
+
+// Begin acquires a connection from the instance chosen by the balance strategy and starts a transaction on it.
+// The connection is returned to the pool when the transaction is committed or rolled back.
+func (p *Pool) Begin(ctx context.Context) (pgx.Tx, error) {
+	return p.BeginTx(ctx, pgx.TxOptions{})
+}
+
+// BeginTx acquires a connection from the instance chosen by the balance strategy and starts a transaction
+// with txOptions on it. The connection is returned to the pool when the transaction is committed or rolled back,
+// and the span of the transaction ends then as well.
+//
+// Isolation level, access mode and deferrable mode are not supported by Picodata. If any of them is set,
+// BeginTx returns *TxOptionNotSupportedError.
+func (p *Pool) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	const op = "pool: BeginTx"
+
+	if err := checkTxOptions(txOptions); err != nil {
+		return nil, err
+	}
+
+	// NOTE: nothing is done before the transaction is started, so it's safe to retry.
+	var tx *Tx
+	span, err := p.withRetrySpan(p.routingContext(ctx, RoutingModePreferLeader), spanBegin, true, func(ctx context.Context, inst *instance) error {
+		pgxTx, err := inst.pool.BeginTx(ctx, txOptions)
+		if err != nil {
+			return err
+		}
+		tx = &Tx{Tx: pgxTx, address: inst.address}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	tx.span = span
+
+	return tx, nil
+}
+
+// BeginFunc starts a transaction and calls fn with it. If fn does not return an error,
+// the transaction is committed. Otherwise, the transaction is rolled back, which doesn't undo
+// statements fn has already executed, see [Tx].
+// The whole transaction runs on a single instance chosen by the balance strategy.
+func (p *Pool) BeginFunc(ctx context.Context, fn func(pgx.Tx) error) error {
+	return pgx.BeginFunc(ctx, p, fn)
+}
+
+// BeginTxFunc is the same as BeginFunc, but starts the transaction with txOptions. See [Pool.BeginTx].
+func (p *Pool) BeginTxFunc(ctx context.Context, txOptions pgx.TxOptions, fn func(pgx.Tx) error) error {
+	return pgx.BeginTxFunc(ctx, p, txOptions, fn)
+}
+
+// Begin starts a transaction on the instance the connection is bound to.
+func (c *Conn) Begin(ctx context.Context) (pgx.Tx, error) {
+	return c.BeginTx(ctx, pgx.TxOptions{})
+}
+
+// BeginTx starts a transaction with txOptions on the instance the connection is bound to.
+// See [Pool.BeginTx] for the list of unsupported options.
+func (c *Conn) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	if err := checkTxOptions(txOptions); err != nil {
+		return nil, err
+	}
+
+	tx, err := c.conn.BeginTx(ctx, txOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{Tx: tx, address: c.address}, nil
+}
+
+// checkTxOptions returns an error for transaction characteristics
+// that can't be expressed in Picodata SQL dialect.
+func checkTxOptions(txOptions pgx.TxOptions) error {
+	if txOptions.IsoLevel != "" {
+		return &TxOptionNotSupportedError{Option: "isolation level", Value: string(txOptions.IsoLevel)}
+	}
+	if txOptions.AccessMode != "" {
+		return &TxOptionNotSupportedError{Option: "access mode", Value: string(txOptions.AccessMode)}
+	}
+	if txOptions.DeferrableMode != "" {
+		return &TxOptionNotSupportedError{Option: "deferrable mode", Value: string(txOptions.DeferrableMode)}
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package picodata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTx(t *testing.T) {
+	t.Run("TestCheckTxOptions", func(t *testing.T) {
+		assert.NoError(t, checkTxOptions(pgx.TxOptions{}))
+		assert.NoError(t, checkTxOptions(pgx.TxOptions{BeginQuery: "BEGIN"}))
+
+		unsupported := []pgx.TxOptions{
+			{IsoLevel: pgx.Serializable},
+			{AccessMode: pgx.ReadOnly},
+			{DeferrableMode: pgx.Deferrable},
+		}
+		for _, opts := range unsupported {
+			var optErr *TxOptionNotSupportedError
+			assert.ErrorAs(t, checkTxOptions(opts), &optErr)
+		}
+	})
+
+	t.Run("TestBeginTxUnsupportedOption", func(t *testing.T) {
+		pool := &Pool{provider: newConnectionProvider(newMockPool("127.0.0.1", 1), 1)}
+
+		tx, err := pool.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.RepeatableRead})
+
+		var optErr *TxOptionNotSupportedError
+		require.ErrorAs(t, err, &optErr)
+		assert.Equal(t, "isolation level", optErr.Option)
+		assert.Equal(t, string(pgx.RepeatableRead), optErr.Value)
+		assert.Nil(t, tx)
+	})
+
+	t.Run("TestBeginFuncUnreachableInstance", func(t *testing.T) {
+		pool := &Pool{provider: newConnectionProvider(newMockPool("127.0.0.1", 1), 1)}
+
+		called := false
+		err := pool.BeginFunc(context.Background(), func(pgx.Tx) error {
+			called = true
+			return nil
+		})
+
+		assert.Error(t, err)
+		assert.False(t, called)
+	})
+
+	t.Run("TestNestedBegin", func(t *testing.T) {
+		tx := &Tx{address: "127.0.0.1:5432"}
+
+		nested, err := tx.Begin(context.Background())
+		assert.ErrorIs(t, err, ErrSavepointNotSupported)
+		assert.Nil(t, nested)
+	})
+}
+
+func TestIntegrationTx(t *testing.T) {
+	pool := newIntegrationPool(t, WithDisableTopologyManaging())
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx, "CREATE TABLE IF NOT EXISTS tx_test (id INT PRIMARY KEY, name TEXT) DISTRIBUTED BY (id)")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := pool.Exec(ctx, "DROP TABLE IF EXISTS tx_test")
+		assert.NoError(t, err)
+	})
+
+	t.Run("TestBeginFuncCommit", func(t *testing.T) {
+		var tx pgx.Tx
+		err := pool.BeginFunc(ctx, func(fnTx pgx.Tx) error {
+			tx = fnTx
+			address := fnTx.(*Tx).Address()
+
+			// Every statement runs on the instance the transaction has been started on
+			for id := range 3 {
+				config := fnTx.Conn().Config()
+				require.Equal(t, address, fmt.Sprintf("%s:%d", config.Host, config.Port))
+				if _, err := fnTx.Exec(ctx, "INSERT INTO tx_test VALUES ($1, $2)", id, "committed"); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+
+		// The transaction is committed and its connection is returned to the pool
+		_, err = tx.Exec(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, pgx.ErrTxClosed)
+		assert.Equal(t, int32(0), pool.Stat().AcquiredConns())
+
+		var count int
+		require.NoError(t, pool.QueryRow(ctx, "SELECT count(*) FROM tx_test WHERE name = 'committed'").Scan(&count))
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("TestBeginFuncRollback", func(t *testing.T) {
+		fnErr := errors.New("fn failed")
+
+		var tx pgx.Tx
+		err := pool.BeginFunc(ctx, func(fnTx pgx.Tx) error {
+			tx = fnTx
+			if _, err := fnTx.Exec(ctx, "INSERT INTO tx_test VALUES ($1, $2)", 10, "rolled back"); err != nil {
+				return err
+			}
+			return fnErr
+		})
+		assert.ErrorIs(t, err, fnErr)
+
+		// The transaction is rolled back and its connection is returned to the pool
+		_, err = tx.Exec(ctx, "SELECT 1")
+		assert.ErrorIs(t, err, pgx.ErrTxClosed)
+		assert.Equal(t, int32(0), pool.Stat().AcquiredConns())
+
+		// Picodata applies every statement at once, so rollback doesn't undo the insert
+		var count int
+		require.NoError(t, pool.QueryRow(ctx, "SELECT count(*) FROM tx_test WHERE name = 'rolled back'").Scan(&count))
+		assert.Equal(t, 1, count)
+	})
+}